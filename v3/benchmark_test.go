@@ -0,0 +1,289 @@
+package haljson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// benchmarkCollection returns a HAL document embedding size orders, each
+// with links, state and an embedded customer
+func benchmarkCollection(size int) []byte {
+	items := make([]string, size)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{
+			"_links": {
+				"self": {"href": "/orders/%[1]d"},
+				"ea:basket": {"href": "/baskets/%[1]d"},
+				"ea:customer": {"href": "/customers/%[1]d", "title": "Customer %[1]d"}
+			},
+			"_embedded": {
+				"ea:customer": {"_links": {"self": {"href": "/customers/%[1]d"}}, "name": "Customer %[1]d", "vip": %[2]t}
+			},
+			"total": %[1]d.5,
+			"currency": "USD",
+			"status": "shipped",
+			"tags": ["a", "b", "c"],
+			"shipping": {"street": "Main St", "number": %[1]d, "city": "Springfield"}
+		}`, i, i%2 == 0)
+	}
+	return []byte(`{
+		"_links": {
+			"self": {"href": "/orders"},
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+			"next": {"href": "/orders?page=2"},
+			"ea:find": {"href": "/orders{?id}", "templated": true}
+		},
+		"_embedded": {"ea:order": [` + strings.Join(items, ",") + `]},
+		"currentlyProcessing": 14,
+		"shippedToday": 20
+	}`)
+}
+
+// remarshalResource decodes a resource the way UnmarshalJSON used to, by
+// decoding it as any and marshaling every section and value again. It is
+// kept as a reference for the benchmarks.
+func remarshalResource(b []byte, r *Resource[any], opts *decodeOptions, path string) error {
+	temp := make(map[string]any)
+	err := json.Unmarshal(b, &temp)
+	if err != nil {
+		return err
+	}
+	r.Links = NewLinks()
+	r.Links.parent = opts.parent
+	if v, ok := temp[LINKS]; ok {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		err = r.Links.unmarshalHAL(data, opts, pointer(path, LINKS))
+		if err != nil {
+			return err
+		}
+	}
+	delete(temp, LINKS)
+	r.Embeds = NewEmbeds()
+	if v, ok := temp[EMBEDDED]; ok {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		err = opts.within(r.Links, func() error {
+			return remarshalEmbeds(data, r.Embeds, opts, pointer(path, EMBEDDED))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	delete(temp, EMBEDDED)
+	r.Data = make(map[string]any)
+	for k, v := range temp {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var value any
+		err = json.Unmarshal(data, &value)
+		if err != nil {
+			return err
+		}
+		r.Data[k] = value
+	}
+	return nil
+}
+
+// remarshalEmbeds decodes embeds the way UnmarshalJSON used to
+func remarshalEmbeds(b []byte, e *Embeds, opts *decodeOptions, path string) error {
+	temp := make(map[string]any)
+	err := json.Unmarshal(b, &temp)
+	if err != nil {
+		return err
+	}
+	e.Relations = make(map[string][]Resource[any])
+	for _, k := range orderedKeys(temp, nil, defaultEncodeOptions) {
+		e.order = append(e.order, k)
+		data, err := json.Marshal(temp[k])
+		if err != nil {
+			return err
+		}
+		if _, ok := temp[k].(map[string]any); ok {
+			var res Resource[any]
+			err = remarshalResource(data, &res, opts, pointer(path, k))
+			if err != nil {
+				return err
+			}
+			e.Relations[k] = []Resource[any]{res}
+			e.SetCardinality(k, Single)
+			continue
+		}
+		var items []json.RawMessage
+		err = json.Unmarshal(data, &items)
+		if err != nil {
+			return err
+		}
+		res := make([]Resource[any], len(items))
+		for i, item := range items {
+			err = remarshalResource(item, &res[i], opts, index(pointer(path, k), i))
+			if err != nil {
+				return err
+			}
+		}
+		e.Relations[k] = res
+	}
+	return nil
+}
+
+func TestUnmarshalMatchesRemarshal(t *testing.T) {
+	data := benchmarkCollection(10)
+
+	var expected Resource[any]
+	assert.NoError(t, remarshalResource(data, &expected, newDecodeOptions(), ""))
+	var r Resource[any]
+	assert.NoError(t, json.Unmarshal(data, &r))
+	assert.Equal(t, expected, r)
+}
+
+func BenchmarkUnmarshalResource(b *testing.B) {
+	for _, size := range []int{1, 100, 1000} {
+		data := benchmarkCollection(size)
+
+		b.Run(fmt.Sprintf("size=%d/single-pass", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var r Resource[any]
+				err := r.UnmarshalJSON(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("size=%d/remarshal", size), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var r Resource[any]
+				err := remarshalResource(data, &r, newDecodeOptions(), "")
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshalEmbeds(b *testing.B) {
+	var sections struct {
+		Embedded json.RawMessage `json:"_embedded"`
+	}
+	err := json.Unmarshal(benchmarkCollection(100), &sections)
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := []byte(sections.Embedded)
+
+	b.Run("single-pass", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var e Embeds
+			err := e.UnmarshalJSON(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("remarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var e Embeds
+			err := remarshalEmbeds(data, &e, newDecodeOptions(), "")
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// benchmarkResource returns the resource decoded from benchmarkCollection
+func benchmarkResource(tb testing.TB, size int) *Resource[any] {
+	var r Resource[any]
+	err := json.Unmarshal(benchmarkCollection(size), &r)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return &r
+}
+
+func TestMarshalAllocations(t *testing.T) {
+	small := NewResource[any]()
+	small.Self("/orders/1")
+	small.AddLink("next", &Link{Href: "/orders?page=2"})
+	small.Set("currency", "USD")
+	small.Set("total", 30.5)
+	small.Set("vip", true)
+	large := benchmarkResource(t, 10)
+	buffer := make([]byte, 0, 8192)
+
+	tests := []struct {
+		name   string
+		budget float64
+		fn     func()
+	}{
+		// One allocation per map of the document for its sorted keys
+		{"small AppendJSON", 2, func() { _, _ = small.AppendJSON(buffer[:0]) }},
+		{"small MarshalJSON", 3, func() { _, _ = small.MarshalJSON() }},
+		{"links AppendJSON", 1, func() { _, _ = small.Links.AppendJSON(buffer[:0]) }},
+		{"collection AppendJSON", 60, func() { _, _ = large.AppendJSON(buffer[:0]) }},
+		{"collection MarshalJSON", 61, func() { _, _ = large.MarshalJSON() }},
+	}
+	for _, tt := range tests {
+		allocs := testing.AllocsPerRun(100, tt.fn)
+		assert.LessOrEqual(t, allocs, tt.budget, tt.name)
+	}
+}
+
+func BenchmarkMarshalResource(b *testing.B) {
+	for _, size := range []int{1, 100, 1000} {
+		r := benchmarkResource(b, size)
+
+		b.Run(fmt.Sprintf("size=%d/AppendJSON", size), func(b *testing.B) {
+			b.ReportAllocs()
+			var buffer []byte
+			for i := 0; i < b.N; i++ {
+				var err error
+				buffer, err = r.AppendJSON(buffer[:0])
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.SetBytes(int64(len(buffer)))
+		})
+
+		b.Run(fmt.Sprintf("size=%d/MarshalJSON", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := r.MarshalJSON()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("size=%d/json.Marshal", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := json.Marshal(r)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
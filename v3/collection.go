@@ -0,0 +1,493 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"reflect"
+	"slices"
+)
+
+// State properties written by Collection
+const (
+	COUNT = "count"
+	TOTAL = "total"
+)
+
+// Collection is a Resource embedding a page of items of type T under a
+// single relation, with count and total state. Links, other embedded
+// resources and other state are kept in the Resource.
+//
+// The items are part of the document for ResolveHrefs, RelativizeHrefs,
+// ExpandCuries, CompactCuries, Validate and Walk. Items that are not a
+// Resource or StateResource, such as structs with `hal` tags, keep the
+// relations of their tags. Walk visits them as the resource they encode
+// to and decodes them back when a WalkFunc changed it.
+type Collection[T any] struct {
+	*Resource[any]
+	// Rel is the relation the items are embedded under, item by default
+	Rel   string
+	Items []T
+	// Total is the number of items in the whole collection, it is left out
+	// when nil as the total is not known
+	Total *int
+}
+
+// NewCollection creates a Collection embedding items under rel, with an
+// unknown total
+func NewCollection[T any](rel string, items []T) *Collection[T] {
+	return &Collection[T]{
+		Resource: NewResource[any](),
+		Rel:      rel,
+		Items:    items,
+	}
+}
+
+// rel returns the relation the items are embedded under
+func (c *Collection[T]) rel() string {
+	return withDefault(c.Rel, string(RelItem))
+}
+
+// Paginate writes the pagination links of the collection, as Links.Paginate
+// does, and sets Total when paging knows it
+func (c *Collection[T]) Paginate(base *url.URL, paging Paging) error {
+	err := c.Resource.Paginate(base, paging)
+	if err != nil {
+		return err
+	}
+	total := -1
+	switch p := paging.(type) {
+	case PageNumber:
+		total = p.Total
+	case OffsetLimit:
+		total = p.Total
+	}
+	if total >= 0 {
+		c.Total = &total
+	}
+	return nil
+}
+
+// links returns the Links of the collection
+func (c *Collection[T]) links() *Links {
+	if c.Resource == nil {
+		return nil
+	}
+	return c.Links
+}
+
+// embeds returns the embedded resources of the collection, the items
+// included, so that EmbedsOf sees them. The items are held by pointer when
+// that makes them a Resource or StateResource. Replacing or removing items
+// through the returned Embeds does not change Items, Walk writes them back.
+func (c *Collection[T]) embeds() *Embeds {
+	items := make([]any, 0, len(c.Items))
+	for i := range c.Items {
+		if node, ok := c.resourceItem(i); ok {
+			items = append(items, node)
+		} else if !isNil(c.Items[i]) {
+			items = append(items, c.Items[i])
+		}
+	}
+	return c.embedsView(items)
+}
+
+// embedsView returns the embedded resources of the collection with items
+// under its relation
+func (c *Collection[T]) embedsView(items []any) *Embeds {
+	view := NewEmbeds()
+	if c.Resource != nil && c.Embeds != nil {
+		// The slices are shared so that resources can be changed in place
+		for rel, resources := range c.Embeds.Relations {
+			view.Relations[rel] = resources
+		}
+		for rel, values := range c.Embeds.Values {
+			if view.Values == nil {
+				view.Values = make(map[string][]any, len(c.Embeds.Values))
+			}
+			view.Values[rel] = values
+		}
+		for rel, cardinality := range c.Embeds.cardinality {
+			view.SetCardinality(rel, cardinality)
+		}
+		view.order = append(view.order, c.Embeds.order...)
+	}
+	rel := c.rel()
+	view.Remove(rel)
+	for _, item := range items {
+		view.addValue(rel, item)
+	}
+	return view
+}
+
+// walkEmbeds walks the embedded resources of the collection for Walk, the
+// items included. Items that are not a Resource or StateResource are walked
+// as the resource they convert to, and decoded back when it changed.
+// Replaced and removed items are written back to Items.
+func (c *Collection[T]) walkEmbeds(parent *Node, fn WalkFunc) error {
+	rel := c.rel()
+	var nodes []any
+	// origins holds the index in Items of each node, encodings the
+	// encoding of the nodes that were converted
+	var origins []int
+	encodings := make(map[int][]byte)
+	for i := range c.Items {
+		node, converted, err := c.itemNode(i)
+		if err != nil {
+			return itemError(c.itemPath(i), err)
+		}
+		if isNil(node) {
+			continue
+		}
+		if converted {
+			b, err := marshalValue(node, defaultEncodeOptions)
+			if err != nil {
+				return itemError(c.itemPath(i), err)
+			}
+			encodings[len(nodes)] = b
+		}
+		nodes = append(nodes, node)
+		origins = append(origins, i)
+	}
+
+	replaced := nodes
+	itemsReplaced := false
+	err := walkEmbeds(c.embedsView(nodes), parent, fn, func(r string, embeds ...any) error {
+		if r == rel {
+			replaced, itemsReplaced = embeds, true
+			return nil
+		}
+		return c.Embeds.Replace(r, embeds...)
+	})
+
+	items := make([]T, 0, len(replaced))
+	for i, embed := range replaced {
+		path := c.itemPath(i)
+		n := slices.IndexFunc(nodes, func(node any) bool { return sameEmbed(node, embed) })
+		if n < 0 {
+			item, itemErr := c.itemFrom(embed, path)
+			if itemErr != nil {
+				return itemErr
+			}
+			items = append(items, item)
+			continue
+		}
+		item := c.Items[origins[n]]
+		if before, ok := encodings[n]; ok {
+			b, itemErr := marshalValue(embed, defaultEncodeOptions)
+			if itemErr == nil && !bytes.Equal(before, b) {
+				// Decoding onto the item keeps what its encoding leaves out
+				itemErr = decodeInto(b, reflect.ValueOf(&item).Elem(), &decodeOptions{parent: c.links()}, path)
+				if itemErr == nil && !itemsReplaced {
+					c.Items[origins[n]] = item
+				}
+			}
+			if itemErr != nil {
+				return itemError(path, itemErr)
+			}
+		}
+		items = append(items, item)
+	}
+	if itemsReplaced {
+		c.Items = items
+	}
+	return err
+}
+
+// itemFrom returns embed as an item, converting it through its encoding
+// when it is not a T
+func (c *Collection[T]) itemFrom(embed any, path string) (T, error) {
+	if item, ok := embed.(T); ok {
+		return item, nil
+	}
+	if item, ok := embed.(*T); ok && item != nil {
+		return *item, nil
+	}
+	var item T
+	b, err := marshalValue(embed, defaultEncodeOptions)
+	if err == nil {
+		err = decodeInto(b, reflect.ValueOf(&item).Elem(), &decodeOptions{parent: c.links()}, path)
+	}
+	if err != nil {
+		return item, itemError(path, err)
+	}
+	return item, nil
+}
+
+// resourceItem returns item i as a Resource or StateResource that changes
+// it in place, when it is one
+func (c *Collection[T]) resourceItem(i int) (resourceNode, bool) {
+	if node, ok := any(&c.Items[i]).(resourceNode); ok {
+		return node, true
+	}
+	node, ok := any(c.Items[i]).(resourceNode)
+	return node, ok && !isNil(node)
+}
+
+// itemPath returns the JSON Pointer of item i
+func (c *Collection[T]) itemPath(i int) string {
+	return index(pointer(pointer("", EMBEDDED), c.rel()), i)
+}
+
+// itemNode returns item i as a resource. Items that are not a Resource or
+// StateResource are converted to a *Resource[any] through their encoding,
+// converted reports whether they were.
+func (c *Collection[T]) itemNode(i int) (node any, converted bool, err error) {
+	if node, ok := c.resourceItem(i); ok {
+		return node, false, nil
+	}
+	if isNil(c.Items[i]) {
+		return c.Items[i], false, nil
+	}
+	b, err := marshalValue(c.Items[i], defaultEncodeOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	r := &Resource[any]{}
+	err = r.unmarshalHAL(b, &decodeOptions{parent: c.links()}, c.itemPath(i))
+	if err != nil {
+		return nil, false, err
+	}
+	return r, true, nil
+}
+
+// rewriteItems calls rewrite for the resource of the collection, then for
+// each item. Converted items are decoded back once rewritten.
+func (c *Collection[T]) rewriteItems(rewrite func(node resourceNode) error) error {
+	if c.Resource != nil {
+		err := rewrite(c.Resource)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range c.Items {
+		path := c.itemPath(i)
+		node, converted, err := c.itemNode(i)
+		if err != nil {
+			return itemError(path, err)
+		}
+		resource, ok := node.(resourceNode)
+		if !ok {
+			continue
+		}
+		err = rewrite(resource)
+		if err != nil {
+			return itemError(path, err)
+		}
+		if converted {
+			b, err := marshalValue(resource, defaultEncodeOptions)
+			if err == nil {
+				err = decodeInto(b, reflect.ValueOf(&c.Items[i]).Elem(), &decodeOptions{parent: c.links()}, path)
+			}
+			if err != nil {
+				return itemError(path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// itemError reports err, found within the item at path
+func itemError(path string, err error) error {
+	var pathErr *PathError
+	if errors.As(err, &pathErr) {
+		return &PathError{Path: path + pathErr.Path, Err: pathErr.Err}
+	}
+	return &PathError{Path: path, Err: err}
+}
+
+// ResolveHrefs resolves every href of the collection and of its items, as
+// Resource.ResolveHrefs does
+func (c *Collection[T]) ResolveHrefs(base *url.URL) error {
+	return c.rewriteItems(func(node resourceNode) error {
+		return rewriteHrefs(node, func(href string, templated bool) (string, error) {
+			return resolveHref(base, href, templated)
+		})
+	})
+}
+
+// RelativizeHrefs rewrites the absolute hrefs of the collection and of its
+// items as relative references, as Resource.RelativizeHrefs does
+func (c *Collection[T]) RelativizeHrefs(base *url.URL) error {
+	return c.rewriteItems(func(node resourceNode) error {
+		return rewriteHrefs(node, func(href string, templated bool) (string, error) {
+			return relativizeHref(base, href, templated)
+		})
+	})
+}
+
+// ExpandCuries rewrites every CURIE relation name of the collection, Rel
+// and its items included, into the full URI of the relation, as
+// Resource.ExpandCuries does
+func (c *Collection[T]) ExpandCuries() {
+	c.rewriteRels(expandRel)
+}
+
+// CompactCuries rewrites every full URI relation name of the collection,
+// Rel and its items included, into a CURIE, as Resource.CompactCuries does
+func (c *Collection[T]) CompactCuries() {
+	c.rewriteRels(compactRel)
+}
+
+// rewriteRels renames the relations of the collection and of its items
+func (c *Collection[T]) rewriteRels(rename func(scope []Curie, rel string) string) {
+	links := c.links()
+	if c.Resource != nil {
+		rewriteRels(c.Resource, links.parent.curieScope(), rename)
+	}
+	scope := links.curieScope()
+	if rel := rename(scope, c.rel()); rel != c.rel() {
+		c.Rel = rel
+	}
+	for i := range c.Items {
+		if node, ok := c.resourceItem(i); ok {
+			rewriteRels(node, scope, rename)
+		}
+	}
+}
+
+// Validate checks the collection and its items, as Resource.Validate does
+func (c *Collection[T]) Validate() error {
+	items := make([]any, len(c.Items))
+	for i := range c.Items {
+		node, _, err := c.itemNode(i)
+		if err != nil {
+			return itemError(c.itemPath(i), err)
+		}
+		items[i] = node
+	}
+	r, err := c.document(items)
+	if err != nil {
+		return err
+	}
+	return validate(r)
+}
+
+// MarshalJSON marshals the collection, embedding its items as an array
+func (c *Collection[T]) MarshalJSON() ([]byte, error) {
+	return marshalPooled(c, defaultEncodeOptions)
+}
+
+// AppendJSON appends the encoding of c to dst and returns the extended
+// buffer, as MarshalJSON does without allocating a new one
+func (c *Collection[T]) AppendJSON(dst []byte) ([]byte, error) {
+	return c.appendHAL(dst, defaultEncodeOptions)
+}
+
+// appendHAL appends the collection to dst using the given options
+func (c *Collection[T]) appendHAL(dst []byte, opts *encodeOptions) ([]byte, error) {
+	items := make([]any, len(c.Items))
+	for i, item := range c.Items {
+		items[i] = item
+	}
+	r, err := c.document(items)
+	if err != nil {
+		return nil, err
+	}
+	// Items streamed by Encoder.EncodeStream are counted once written
+	if s := opts.stream; s != nil && !s.started && s.producers[c.rel()] != nil {
+		r.Set(COUNT, streamedCount{stream: s, rel: c.rel()})
+	}
+	return r.appendHAL(dst, opts)
+}
+
+// document returns the resource the collection is written as, embedding
+// items under its relation. It shares the links of the collection.
+func (c *Collection[T]) document(items []any) (*Resource[any], error) {
+	base := c.Resource
+	if base == nil {
+		base = NewResource[any]()
+	}
+	r := &Resource[any]{
+		Links:  base.Links,
+		Embeds: NewEmbeds(),
+		Data:   make(map[string]any, len(base.Data)+2),
+		order:  append([]string(nil), base.order...),
+	}
+	if base.Embeds != nil {
+		r.Embeds = base.Embeds.clone()
+	}
+	for key, value := range base.Data {
+		r.Data[key] = value
+	}
+
+	rel := c.rel()
+	r.Embeds.Remove(rel)
+	r.Embeds.add(rel)
+	for i, item := range items {
+		err := r.Embeds.AddEmbed(rel, item)
+		if err != nil {
+			return nil, &PathError{Path: c.itemPath(i), Err: err}
+		}
+	}
+	r.Set(COUNT, len(items))
+	if c.Total != nil {
+		r.Set(TOTAL, *c.Total)
+	}
+	return r, nil
+}
+
+// UnmarshalJSON unmarshals the collection, decoding its items as T
+func (c *Collection[T]) UnmarshalJSON(b []byte) error {
+	return c.unmarshalHAL(b, newDecodeOptions(), "")
+}
+
+// unmarshalHAL unmarshals a collection found at path using the given options
+func (c *Collection[T]) unmarshalHAL(data []byte, opts *decodeOptions, path string) error {
+	// The items are left out of the resource and only decoded as T
+	rel := c.rel()
+	r := NewResource[any]()
+	itemsData, err := r.unmarshalExcept(data, opts, path, rel)
+	if err != nil {
+		return err
+	}
+	c.Items = []T{}
+	if itemsData != nil {
+		relPath := pointer(pointer(path, EMBEDDED), rel)
+		// A single item may be embedded as an object rather than an array
+		raw := []json.RawMessage{itemsData}
+		single := isObject(itemsData)
+		if !single {
+			if !isArray(itemsData) {
+				return typeError(relPath, "expected object or array")
+			}
+			err = json.Unmarshal(itemsData, &raw)
+			if err != nil {
+				return err
+			}
+		}
+		err = opts.within(r.Links, func() error {
+			for i, itemData := range raw {
+				itemPath := index(relPath, i)
+				if single {
+					itemPath = relPath
+				}
+				var item T
+				err := decodeInto(itemData, reflect.ValueOf(&item).Elem(), opts, itemPath)
+				if err != nil {
+					return err
+				}
+				c.Items = append(c.Items, item)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Total = nil
+	if total, ok := r.Data[TOTAL]; ok {
+		if number, ok := total.(float64); ok {
+			count := int(number)
+			c.Total = &count
+		} else {
+			invalidType(opts, pointer(path, TOTAL), "number")
+		}
+	}
+	delete(r.Data, COUNT)
+	delete(r.Data, TOTAL)
+	c.Resource = r
+	return nil
+}
@@ -0,0 +1,243 @@
+package haljson
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	Self string `hal:"self"`
+	Name string `json:"name"`
+}
+
+func TestCollectionMarshal(t *testing.T) {
+	c := NewCollection("ea:order", []testItem{{Self: "/orders/1", Name: "one"}, {Self: "/orders/2", Name: "two"}})
+	c.Self("/orders?page=1")
+	c.AddCurie(&Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true})
+	c.Set("currency", "USD")
+	base, _ := url.Parse("/orders")
+	assert.Nil(t, c.Paginate(base, PageNumber{Page: 1, Size: 2, Total: 3}))
+	assert.Equal(t, 3, *c.Total)
+
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"_links": {
+			"self": {"href": "/orders?page=1"},
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+			"first": {"href": "/orders?page=1&size=2"},
+			"next": {"href": "/orders?page=2&size=2"},
+			"last": {"href": "/orders?page=2&size=2"}
+		},
+		"_embedded": {
+			"ea:order": [
+				{"_links": {"self": {"href": "/orders/1"}}, "name": "one"},
+				{"_links": {"self": {"href": "/orders/2"}}, "name": "two"}
+			]
+		},
+		"count": 2,
+		"currency": "USD",
+		"total": 3
+	}`, string(b))
+
+	// Marshaling does not add the items to the resource
+	assert.Empty(t, c.Embeds.Rels())
+	assert.Equal(t, []string{"currency"}, c.Resource.order)
+}
+
+func TestCollectionMarshalEmpty(t *testing.T) {
+	var c Collection[testItem]
+	b, err := json.Marshal(&c)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_embedded":{"item":[]},"count":0}`, string(b), "an unknown total is left out")
+
+	c = *NewCollection[testItem]("", nil)
+	total := 0
+	c.Total = &total
+	b, err = json.Marshal(&c)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_embedded":{"item":[]},"count":0,"total":0}`, string(b))
+}
+
+func TestCollectionUnmarshal(t *testing.T) {
+	doc := `{
+		"_links": {
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+			"next": {"href": "/orders?page=2"}
+		},
+		"_embedded": {
+			"ea:order": [
+				{"_links": {"self": {"href": "/orders/1"}, "ea:customer": {"href": "/customers/1"}}, "name": "one"},
+				{"_links": {"self": {"href": "/orders/2"}}, "name": "two"}
+			],
+			"ea:summary": {"name": "summary"}
+		},
+		"count": 2,
+		"total": 10,
+		"currency": "USD"
+	}`
+
+	c := Collection[testItem]{Rel: "ea:order"}
+	assert.Nil(t, NewDecoder(strings.NewReader(doc)).Strict().Decode(&c))
+	assert.Equal(t, []testItem{{Self: "/orders/1", Name: "one"}, {Self: "/orders/2", Name: "two"}}, c.Items)
+	assert.Equal(t, 10, *c.Total)
+	assert.Equal(t, map[string]any{"currency": "USD"}, c.Data)
+	assert.Equal(t, []string{"ea:summary"}, c.Embeds.Rels())
+	assert.Equal(t, "/orders?page=2", c.FirstLink("next").Href)
+
+	// Typed resources keep the curies of the collection in scope
+	resources := Collection[*Resource[any]]{Rel: "ea:order"}
+	assert.Nil(t, json.Unmarshal([]byte(doc), &resources))
+	assert.Len(t, resources.Items, 2)
+	assert.Equal(t, "http://example.com/docs/rels/customer", resources.Items[0].Links.ExpandRel("ea:customer"))
+
+	single := Collection[testItem]{}
+	assert.Nil(t, json.Unmarshal([]byte(`{"_embedded": {"item": {"name": "only"}}, "count": 1}`), &single))
+	assert.Equal(t, []testItem{{Name: "only"}}, single.Items)
+	assert.Nil(t, single.Total)
+
+	err := NewDecoder(strings.NewReader(`{"total": "many"}`)).Strict().Decode(&single)
+	assert.ErrorIs(t, pathErrors(t, err)["/total"], ErrInvalidType)
+
+	// Items are decoded once, their problems are reported once
+	err = NewDecoder(strings.NewReader(`{"_embedded": {"item": [{"_links": {"self": {"href": "/", "title": 1}}}]}}`)).Strict().Decode(&single)
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 1)
+	assert.ErrorIs(t, pathErrors(t, err)["/_embedded/item/0/_links/self/title"], ErrInvalidType)
+}
+
+func TestCollectionRoundTrip(t *testing.T) {
+	c := NewCollection("orders", []map[string]any{{"id": 1.0}, {"id": 2.0}})
+	total := 2
+	c.Total = &total
+	b, err := json.Marshal(c)
+	assert.Nil(t, err)
+
+	decoded := Collection[map[string]any]{Rel: "orders"}
+	assert.Nil(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, c.Items, decoded.Items)
+	assert.Equal(t, 2, *decoded.Total)
+
+	again, err := json.Marshal(&decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), string(again))
+}
+
+func TestCollectionItemsInDocument(t *testing.T) {
+	type item struct {
+		Self string `hal:"self"`
+		Edit *Link  `hal:"link,rel=edit"`
+		Name string `json:"name"`
+	}
+	base, _ := url.Parse("https://example.com/api/")
+	c := NewCollection("ea:item", []item{{Self: "/items/1", Name: "one"}, {Self: "items/2", Name: "two"}})
+	c.Self("/items")
+	c.AddCurie(&Curie{Name: "ea", Href: "https://example.com/rels/{rel}", Templated: true})
+
+	// Items with hal tags are rewritten through their encoding
+	assert.Nil(t, c.ResolveHrefs(base))
+	assert.Equal(t, "https://example.com/items", c.Links.Self.Href)
+	assert.Equal(t, "https://example.com/items/1", c.Items[0].Self)
+	assert.Equal(t, "https://example.com/api/items/2", c.Items[1].Self)
+	assert.Nil(t, c.RelativizeHrefs(base))
+	assert.Equal(t, "/items/1", c.Items[0].Self)
+
+	// Items are validated with their path
+	c.Items[1].Edit = &Link{Title: "Edit"}
+	err := c.Validate()
+	assert.ErrorIs(t, pathErrors(t, err)["/_embedded/ea:item/1/_links/edit/href"], ErrMissingHref)
+	c.Items[1].Edit = nil
+	assert.Nil(t, c.Validate())
+
+	var visited []string
+	assert.Nil(t, Walk(c, func(node *Node) error {
+		visited = append(visited, node.Path)
+		return nil
+	}))
+	assert.Contains(t, visited, "/_embedded/ea:item/1")
+
+	// Resource items are rewritten in place and get their relations renamed
+	resources := NewCollection("ea:item", []*Resource[any]{NewResource[any]()})
+	resources.AddCurie(&Curie{Name: "ea", Href: "https://example.com/rels/{rel}", Templated: true})
+	resources.Items[0].Links.parent = resources.Links
+	assert.Nil(t, resources.Items[0].AddLink("ea:owner", &Link{Href: "/owners/1"}))
+	assert.Nil(t, resources.ResolveHrefs(base))
+	assert.Equal(t, "https://example.com/owners/1", resources.Items[0].FirstLink("ea:owner").Href)
+	resources.ExpandCuries()
+	assert.Equal(t, "https://example.com/rels/item", resources.Rel)
+	assert.True(t, resources.Items[0].HasLink("https://example.com/rels/owner"))
+	resources.CompactCuries()
+	assert.Equal(t, "ea:item", resources.Rel)
+
+	items, err := EmbedsOf[*Resource[any]](resources, "ea:item")
+	assert.Nil(t, err)
+	assert.Same(t, resources.Items[0], items[0])
+}
+
+func TestCollectionWalk(t *testing.T) {
+	type item struct {
+		Self string `hal:"self"`
+		Name string `json:"name"`
+	}
+	c := NewCollection("item", []item{{Self: "/items/1", Name: "one"}, {Self: "/items/2", Name: "two"}})
+	c.AddEmbed("owner", NewResource[any]())
+
+	// Items with hal tags are walked through their encoding, so their links
+	// are visited and changes to them are decoded back
+	var visited []string
+	assert.Nil(t, Walk(c, func(node *Node) error {
+		visited = append(visited, node.Path)
+		if node.Kind == LinkNode && node.Link.Href == "/items/2" {
+			node.Link = &Link{Href: "/items/two"}
+		}
+		return nil
+	}))
+	assert.Equal(t, []string{"", "/_embedded/item/0", "/_embedded/item/0/_links/self",
+		"/_embedded/item/1", "/_embedded/item/1/_links/self", "/_embedded/owner/0"}, visited)
+	assert.Equal(t, []item{{Self: "/items/1", Name: "one"}, {Self: "/items/two", Name: "two"}}, c.Items)
+
+	// Removed and replaced items are written back to Items
+	assert.Nil(t, Walk(c, func(node *Node) error {
+		if node.Kind == ResourceNode && node.Path == "/_embedded/item/0" {
+			node.Resource = nil
+		}
+		return nil
+	}))
+	assert.Equal(t, []item{{Self: "/items/two", Name: "two"}}, c.Items)
+	assert.True(t, c.HasEmbed("owner"))
+
+	assert.Nil(t, Walk(c, func(node *Node) error {
+		if node.Kind == ResourceNode && node.Rel == "item" {
+			node.Resource = item{Self: "/items/3", Name: "three"}
+		}
+		return nil
+	}))
+	assert.Equal(t, []item{{Self: "/items/3", Name: "three"}}, c.Items)
+
+	replacement := NewResource[any]()
+	replacement.Self("/items/4")
+	replacement.Set("name", "four")
+	assert.Nil(t, Walk(c, func(node *Node) error {
+		if node.Kind == ResourceNode && node.Rel == "item" {
+			node.Resource = replacement
+		}
+		return nil
+	}))
+	assert.Equal(t, []item{{Self: "/items/4", Name: "four"}}, c.Items)
+
+	// Replacements that cannot be decoded as an item fail with their path
+	err := Walk(c, func(node *Node) error {
+		if node.Kind == ResourceNode && node.Rel == "item" {
+			node.Resource = 42
+		}
+		return nil
+	})
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_embedded/item/0", pathErr.Path)
+	assert.Len(t, c.Items, 1)
+}
@@ -1,10 +1,25 @@
 package haljson
 
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
 // Curie represents a curie
 type Curie struct {
 	Name      string `json:"name,omitempty"`
 	Href      string `json:"href,omitempty"`
 	Templated bool   `json:"templated,omitempty"`
+	// Extensions holds attributes beyond those defined by HAL, keyed by name
+	Extensions map[string]any `json:"-"`
+}
+
+// curieProperties are the Curie properties defined by HAL
+var curieProperties = map[string]bool{
+	NAME:      true,
+	HREF:      true,
+	TEMPLATED: true,
 }
 
 // SetName sets the Curie name, chainable
@@ -24,3 +39,251 @@ func (c *Curie) SetTemplated(templated bool) *Curie {
 	c.Templated = templated
 	return c
 }
+
+// SetExtension sets an extension attribute, chainable. Keys naming a HAL
+// defined property are ignored when marshaling.
+func (c *Curie) SetExtension(key string, value any) *Curie {
+	if c.Extensions == nil {
+		c.Extensions = make(map[string]any)
+	}
+	c.Extensions[key] = value
+	return c
+}
+
+// Extension returns an extension attribute and whether it was set
+func (c *Curie) Extension(key string) (any, bool) {
+	value, ok := c.Extensions[key]
+	return value, ok
+}
+
+// MarshalJSON marshals a curie with its extension attributes
+func (c Curie) MarshalJSON() ([]byte, error) {
+	return c.appendJSON(nil)
+}
+
+// appendJSON appends the encoding of c to dst, properties in the order of
+// the Curie fields followed by the extension attributes
+func (c *Curie) appendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = appendProperty(dst, NAME, c.Name)
+	dst = appendProperty(dst, HREF, c.Href)
+	if c.Templated {
+		dst = append(appendSeparator(dst), `"templated":true`...)
+	}
+	dst, err := appendExtensions(dst, c.Extensions, curieProperties)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, '}'), nil
+}
+
+// UnmarshalJSON unmarshals a curie, keeping unknown attributes as extensions
+func (c *Curie) UnmarshalJSON(b []byte) error {
+	var properties map[string]any
+	err := json.Unmarshal(b, &properties)
+	if err != nil {
+		return err
+	}
+	*c = curieFromProperties(properties, newDecodeOptions(), "")
+	return nil
+}
+
+// curieFromProperties builds a Curie from decoded JSON properties found at
+// path. HAL properties of the wrong type are ignored, or reported in strict
+// mode, and unknown ones are kept as extensions.
+func curieFromProperties(properties map[string]any, opts *decodeOptions, path string) Curie {
+	var curie Curie
+	for k, v := range properties {
+		switch k {
+		case NAME:
+			if name, ok := v.(string); ok {
+				curie.Name = name
+			} else {
+				invalidType(opts, pointer(path, k), "string")
+			}
+		case HREF:
+			if href, ok := v.(string); ok {
+				curie.Href = href
+			} else {
+				invalidType(opts, pointer(path, k), "string")
+			}
+		case TEMPLATED:
+			if templated, ok := v.(bool); ok {
+				curie.Templated = templated
+			} else {
+				invalidType(opts, pointer(path, k), "boolean")
+			}
+		default:
+			curie.SetExtension(k, v)
+		}
+	}
+	return curie
+}
+
+// expandRel expands a CURIE such as ea:orders into the URI of its relation
+// using the first curie in scope with a matching name. Relations that are
+// not CURIEs, or have no matching templated curie, are returned unchanged.
+func expandRel(scope []Curie, rel string) string {
+	prefix, reference, ok := strings.Cut(rel, ":")
+	if !ok || prefix == "" {
+		return rel
+	}
+	for i := range scope {
+		if scope[i].Name != prefix {
+			continue
+		}
+		expanded, err := scope[i].Expand(reference)
+		if err != nil {
+			return rel
+		}
+		return expanded
+	}
+	return rel
+}
+
+// compactRel compacts the URI of a relation into a CURIE using the first
+// templated curie in scope whose href matches it. URIs that match no curie
+// are returned unchanged.
+func compactRel(scope []Curie, uri string) string {
+	for _, curie := range scope {
+		if !curie.Templated || curie.Name == "" {
+			continue
+		}
+		template, err := ParseURITemplate(curie.Href)
+		if err != nil {
+			continue
+		}
+		vars, ok := template.Match(uri)
+		if !ok {
+			continue
+		}
+		if reference, ok := vars["rel"].(string); ok && reference != "" {
+			return curie.Name + ":" + reference
+		}
+	}
+	return uri
+}
+
+// rewriteRels renames the relations of node and, recursively, of its
+// embedded resources. rename receives the curies in scope, those of the
+// resource itself followed by those of its ancestors, and a relation name.
+func rewriteRels(node resourceNode, scope []Curie, rename func(scope []Curie, rel string) string) {
+	links := node.links()
+	if links != nil {
+		scope = append(append([]Curie(nil), links.Curies...), scope...)
+		for _, rel := range orderedKeys(links.Relations, links.order, &encodeOptions{order: InsertionOrder}) {
+			links.renameRel(rel, rename(scope, rel))
+		}
+	}
+	embeds := node.embeds()
+	if embeds == nil {
+		return
+	}
+	for _, rel := range orderedKeys(embeds.rels(), embeds.order, &encodeOptions{order: InsertionOrder}) {
+		embeds.renameRel(rel, rename(scope, rel))
+	}
+	for _, resources := range embeds.Relations {
+		for i := range resources {
+			rewriteRels(&resources[i], scope, rename)
+		}
+	}
+	for _, values := range embeds.Values {
+		for _, value := range values {
+			if child, ok := value.(resourceNode); ok {
+				rewriteRels(child, scope, rename)
+			}
+		}
+	}
+}
+
+// documentNode returns v as a resource whose curies can be hoisted, or nil
+// when v is not a resource
+func documentNode(v any) (resourceNode, error) {
+	if node, ok := v.(resourceNode); ok {
+		return node, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Struct {
+		addressable := reflect.New(rv.Type())
+		addressable.Elem().Set(rv)
+		if node, ok := addressable.Interface().(resourceNode); ok {
+			return node, nil
+		}
+	}
+	if rv, ok := isHALStruct(rv); ok {
+		return resourceFromStruct(rv, nil)
+	}
+	return nil, nil
+}
+
+// hoistableCuries returns the curies of node and its embedded resources that
+// can be declared once on node, in the order they are found. A curie is only
+// hoisted when every declaration of its name is identical, otherwise moving
+// it could change which curie a nested relation resolves to.
+func hoistableCuries(node resourceNode) []Curie {
+	var found []Curie
+	conflicts := make(map[string]bool)
+	collectCuries(node, func(curie Curie) {
+		for _, c := range found {
+			if c.Name == curie.Name {
+				if !reflect.DeepEqual(c, curie) {
+					conflicts[c.Name] = true
+				}
+				return
+			}
+		}
+		found = append(found, curie)
+	})
+	var hoisted []Curie
+	for _, curie := range found {
+		if !conflicts[curie.Name] {
+			hoisted = append(hoisted, curie)
+		}
+	}
+	return hoisted
+}
+
+// collectCuries calls fn with every curie declared by node and its embedded
+// resources, depth first
+func collectCuries(node resourceNode, fn func(Curie)) {
+	if links := node.links(); links != nil {
+		for _, curie := range links.Curies {
+			fn(curie)
+		}
+	}
+	embeds := node.embeds()
+	if embeds == nil {
+		return
+	}
+	for _, rel := range orderedKeys(embeds.rels(), embeds.order, defaultEncodeOptions) {
+		for i := range embeds.Relations[rel] {
+			collectCuries(&embeds.Relations[rel][i], fn)
+		}
+		for _, value := range embeds.Values[rel] {
+			// Structs with `hal` tags declare their curies once converted
+			if child, err := documentNode(value); err == nil && child != nil {
+				collectCuries(child, fn)
+			}
+		}
+	}
+}
+
+// curiesFor returns the curies l declares when hoisting. The root declares
+// every hoisted curie followed by its own that were not hoisted, other
+// resources only those that were not hoisted.
+func (opts *encodeOptions) curiesFor(l *Links) []Curie {
+	var curies []Curie
+	if l == opts.root {
+		curies = append(curies, opts.hoisted...)
+	}
+	for _, curie := range l.Curies {
+		hoisted := false
+		for _, h := range opts.hoisted {
+			hoisted = hoisted || reflect.DeepEqual(h, curie)
+		}
+		if !hoisted {
+			curies = append(curies, curie)
+		}
+	}
+	return curies
+}
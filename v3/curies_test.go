@@ -1,6 +1,10 @@
 package haljson
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,3 +45,247 @@ func TestCurieSetTemplated(t *testing.T) {
 	curie.SetTemplated(false)
 	assert.False(t, curie.Templated)
 }
+
+func TestCurieExtensions(t *testing.T) {
+	curie := (&Curie{}).SetName("ea").SetHref("/docs/{rel}").SetTemplated(true).SetExtension("x-docs-version", "2")
+
+	value, ok := curie.Extension("x-docs-version")
+	assert.True(t, ok)
+	assert.Equal(t, "2", value)
+
+	b, err := json.Marshal(curie)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"ea","href":"/docs/{rel}","templated":true,"x-docs-version":"2"}`, string(b))
+
+	var links Links
+	err = json.Unmarshal([]byte(`{"curies":[`+string(b)+`]}`), &links)
+	assert.Nil(t, err)
+	assert.Equal(t, *curie, links.Curies[0])
+
+	var decoded Curie
+	assert.Nil(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, *curie, decoded)
+}
+
+func TestLinksExpandAndCompactRel(t *testing.T) {
+	links := NewLinks()
+	links.Curies = []Curie{{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true}}
+
+	assert.Equal(t, "http://example.com/docs/rels/orders", links.ExpandRel("ea:orders"))
+	assert.Equal(t, "ea:orders", links.CompactRel("http://example.com/docs/rels/orders"))
+	assert.Equal(t, "xx:orders", links.ExpandRel("xx:orders"), "unknown prefixes are kept")
+	assert.Equal(t, "next", links.ExpandRel("next"))
+	assert.Equal(t, "http://other.com/orders", links.CompactRel("http://other.com/orders"))
+}
+
+func TestLinksGetEitherForm(t *testing.T) {
+	links := NewLinks()
+	links.Curies = []Curie{{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true}}
+	assert.NoError(t, links.AddLink("ea:orders", &Link{Href: "/orders"}))
+
+	assert.Equal(t, "/orders", links.Get("ea:orders")[0].Href)
+	assert.Equal(t, "/orders", links.Get("http://example.com/docs/rels/orders")[0].Href)
+	assert.Nil(t, links.Get("ea:customers"))
+}
+
+func TestResourceExpandAndCompactCuries(t *testing.T) {
+	r := NewResource[any]()
+	err := json.Unmarshal([]byte(`{
+		"_links": {
+			"self": {"href": "/orders"},
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+			"ea:admin": [{"href": "/admins/2"}]
+		},
+		"_embedded": {
+			"ea:order": [{"_links": {"ea:customer": [{"href": "/customers/7809"}]}}]
+		}
+	}`), r)
+	assert.NoError(t, err)
+
+	r.ExpandCuries()
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"_links": {
+			"self": {"href": "/orders"},
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+			"http://example.com/docs/rels/admin": [{"href": "/admins/2"}]
+		},
+		"_embedded": {
+			"http://example.com/docs/rels/order": [{
+				"_links": {"http://example.com/docs/rels/customer": [{"href": "/customers/7809"}]}
+			}]
+		}
+	}`, string(b), "embedded resources use the curies of their parent")
+
+	r.CompactCuries()
+	b, err = json.Marshal(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"_links": {
+			"self": {"href": "/orders"},
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+			"ea:admin": [{"href": "/admins/2"}]
+		},
+		"_embedded": {
+			"ea:order": [{"_links": {"ea:customer": [{"href": "/customers/7809"}]}}]
+		}
+	}`, string(b))
+}
+
+func TestResourceExpandCuriesMergesRelations(t *testing.T) {
+	r := NewResource[any]()
+	err := json.Unmarshal([]byte(`{
+		"_links": {
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+			"ea:orders": {"href": "/orders/1"},
+			"http://example.com/docs/rels/orders": [{"href": "/orders/2"}]
+		}
+	}`), r)
+	assert.NoError(t, err)
+
+	r.ExpandCuries()
+	links := r.Links.Get("http://example.com/docs/rels/orders")
+	assert.Len(t, links, 2)
+	assert.Equal(t, Many, r.Links.Cardinality("http://example.com/docs/rels/orders"))
+	_, ok := r.Links.Relations["ea:orders"]
+	assert.False(t, ok)
+}
+
+func TestEmbeddedResourceInheritsCuries(t *testing.T) {
+	r := NewResource[any]()
+	r.AddCurie(&Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true})
+
+	order := NewResource[any]()
+	assert.NoError(t, r.AddEmbed("ea:order", order))
+	assert.NoError(t, order.AddLink("ea:customer", &Link{Href: "/customers/7809"}))
+	assert.Equal(t, "http://example.com/docs/rels/customer", order.Links.ExpandRel("ea:customer"))
+	assert.Equal(t, "/customers/7809", order.Links.Get("http://example.com/docs/rels/customer")[0].Href)
+
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"_links": {"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}]},
+		"_embedded": {"ea:order": [{"_links": {"ea:customer": [{"href": "/customers/7809"}]}}]}
+	}`, string(b), "inherited curies are not repeated")
+}
+
+func TestEmbeddedResourceCurieOrder(t *testing.T) {
+	r := NewResource[any]()
+	r.AddCurie(&Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true})
+
+	// Resources may be built before they are embedded, from the bottom up
+	item := NewResource[any]()
+	assert.NoError(t, item.AddLink("ea:basket", &Link{Href: "/baskets/1"}))
+	order := NewStateResource(map[string]int{"total": 1})
+	assert.NoError(t, order.AddLink("ea:customer", &Link{Href: "/customers/1"}))
+	assert.NoError(t, order.AddEmbed("ea:item", item))
+
+	// Their curies are missing until they are embedded
+	err := order.Validate()
+	assert.ErrorIs(t, err, ErrNoCurie)
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_links/ea:customer", pathErr.Path)
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 2)
+
+	assert.NoError(t, r.SetEmbed("ea:order", order))
+	assert.NoError(t, r.Validate())
+	assert.Equal(t, "http://example.com/docs/rels/basket", item.Links.ExpandRel("ea:basket"))
+
+	// Curies that are in scope nowhere are reported where they are used
+	assert.NoError(t, item.AddLink("xx:unknown", &Link{Href: "/"}))
+	err = r.Validate()
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_embedded/ea:order/_embedded/ea:item/0/_links/xx:unknown", pathErr.Path)
+}
+
+func TestEmbeddedCurieShadowsParent(t *testing.T) {
+	r := NewStateResource(struct{}{})
+	r.AddCurie(&Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true})
+	order := NewResource[any]()
+	order.AddCurie(&Curie{Name: "ea", Href: "http://other.com/rels/{rel}", Templated: true})
+	assert.NoError(t, r.SetEmbed("ea:order", order))
+
+	assert.Equal(t, "http://other.com/rels/customer", order.Links.ExpandRel("ea:customer"))
+	assert.Equal(t, "http://example.com/docs/rels/customer", r.Links.ExpandRel("ea:customer"))
+}
+
+func TestStrictDecodeInheritsCuries(t *testing.T) {
+	doc := `{
+		"_links": {"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}]},
+		"_embedded": {
+			"ea:order": [{
+				"_links": {"ea:customer": {"href": "/customers/7809"}, "xx:unknown": {"href": "/"}},
+				"_embedded": {"ea:item": {"_links": {"ea:basket": {"href": "/baskets/1"}}}}
+			}]
+		}
+	}`
+	var r Resource[any]
+	err := NewDecoder(strings.NewReader(doc)).Strict().Decode(&r)
+	found := pathErrors(t, err)
+	assert.Len(t, found, 1)
+	assert.ErrorIs(t, found["/_embedded/ea:order/0/_links/xx:unknown"], ErrNoCurie)
+
+	order := r.Embeds.Relations["ea:order"][0]
+	assert.Equal(t, "http://example.com/docs/rels/customer", order.Links.ExpandRel("ea:customer"))
+	assert.NoError(t, order.AddLink("ea:invoice", &Link{Href: "/invoices/1"}))
+
+	var s StateResource[map[string]any]
+	assert.NoError(t, NewDecoder(strings.NewReader(strings.Replace(doc, `, "xx:unknown": {"href": "/"}`, "", 1))).Strict().Decode(&s))
+}
+
+type testShipment struct {
+	Self   string `hal:"self"`
+	Basket string `hal:"link,rel=ea:basket"`
+}
+
+func TestMarshalStructEmbedInheritsCuries(t *testing.T) {
+	v := struct {
+		Curies    []Curie        `hal:"curies"`
+		Shipments []testShipment `hal:"embed,rel=ea:shipment"`
+	}{
+		Curies:    []Curie{{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true}},
+		Shipments: []testShipment{{Self: "/shipments/1", Basket: "/baskets/1"}},
+	}
+	b, err := Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"_links":{"curies":[{"name":"ea","href":"http://example.com/docs/rels/{rel}","templated":true}]},`+
+		`"_embedded":{"ea:shipment":[{"_links":{"self":{"href":"/shipments/1"},"ea:basket":{"href":"/baskets/1"}}}]}}`, string(b))
+}
+
+func TestEncoderHoistCuries(t *testing.T) {
+	ea := Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true}
+	r := NewResource[any]()
+	r.Self("/orders")
+
+	first := NewResource[any]()
+	first.AddCurie(&ea)
+	first.AddCurie(&Curie{Name: "xx", Href: "http://x.com/{rel}", Templated: true})
+	first.AddLink("ea:customer", &Link{Href: "/customers/1"})
+	second := NewResource[any]()
+	second.AddCurie(&ea)
+	second.AddCurie(&Curie{Name: "xx", Href: "http://y.com/{rel}", Templated: true})
+	second.AddLink("ea:customer", &Link{Href: "/customers/2"})
+	r.AddEmbed("orders", first)
+	r.AddEmbed("orders", second)
+
+	var buffer bytes.Buffer
+	assert.NoError(t, NewEncoder(&buffer).SetHoistCuries(true).Encode(r))
+	assert.JSONEq(t, `{
+		"_links": {
+			"self": {"href": "/orders"},
+			"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}]
+		},
+		"_embedded": {"orders": [
+			{"_links": {"curies": [{"name": "xx", "href": "http://x.com/{rel}", "templated": true}], "ea:customer": [{"href": "/customers/1"}]}},
+			{"_links": {"curies": [{"name": "xx", "href": "http://y.com/{rel}", "templated": true}], "ea:customer": [{"href": "/customers/2"}]}}
+		]}
+	}`, buffer.String(), "conflicting curies stay in place")
+
+	// Hoisting does not change the resource itself
+	assert.Len(t, first.Links.Curies, 2)
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(b), `"name":"ea"`))
+}
@@ -0,0 +1,152 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PathError records a problem with a HAL document and the JSON Pointer
+// (RFC 6901) of the value that caused it
+type PathError struct {
+	Path string
+	Err  error
+}
+
+// Error implements error
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// decodeOptions carries Decoder settings through nested unmarshaling
+type decodeOptions struct {
+	strict bool
+	// errs collects problems found in strict mode
+	errs []error
+	// parent is the Links of the resource being decoded, embedded
+	// resources inherit its curies
+	parent *Links
+}
+
+// within runs decode with parent as the Links embedded resources inherit from
+func (opts *decodeOptions) within(parent *Links, decode func() error) error {
+	saved := opts.parent
+	opts.parent = parent
+	defer func() {
+		opts.parent = saved
+	}()
+	return decode()
+}
+
+// report records a strict mode problem at path
+func (opts *decodeOptions) report(path string, err error) {
+	if opts.strict {
+		opts.errs = append(opts.errs, &PathError{Path: path, Err: err})
+	}
+}
+
+// newDecodeOptions returns the lenient options used by UnmarshalJSON
+func newDecodeOptions() *decodeOptions {
+	return &decodeOptions{}
+}
+
+// halDecoder is implemented by types that unmarshal differently depending on decodeOptions
+type halDecoder interface {
+	unmarshalHAL(data []byte, opts *decodeOptions, path string) error
+}
+
+// Decoder reads HAL documents from an input stream
+type Decoder struct {
+	dec    *json.Decoder
+	strict bool
+}
+
+// NewDecoder returns a new Decoder that reads from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Strict makes the decoder report malformed HAL instead of ignoring it:
+// properties of the wrong type, links without href and curied relations
+// without a matching curie. Each problem is reported as a *PathError.
+func (d *Decoder) Strict() *Decoder {
+	d.strict = true
+	return d
+}
+
+// Decode reads the next HAL document into v, as Unmarshal does. In strict
+// mode every problem found is returned, joined with errors.Join, after v
+// has been populated.
+func (d *Decoder) Decode(v any) error {
+	var raw json.RawMessage
+	err := d.dec.Decode(&raw)
+	if err != nil {
+		return err
+	}
+	opts := &decodeOptions{strict: d.strict}
+	err = unmarshalValue(raw, v, opts, "")
+	if err != nil {
+		return err
+	}
+	return errors.Join(opts.errs...)
+}
+
+// pointer appends key to a JSON Pointer, escaping it per RFC 6901
+func pointer(path string, key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	key = strings.ReplaceAll(key, "/", "~1")
+	return path + "/" + key
+}
+
+// index appends an array index to a JSON Pointer
+func index(path string, i int) string {
+	return fmt.Sprintf("%s/%d", path, i)
+}
+
+// unmarshalObject unmarshals the JSON object found at path, null gives an
+// empty object. Other JSON values fail with a *PathError wrapping
+// ErrInvalidType, as they cannot be decoded at all. Members decoded as
+// json.RawMessage are kept as they are, so that they can be decoded later
+// without marshaling them again.
+func unmarshalObject[V any](b []byte, path string) (map[string]V, error) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) > 0 && trimmed[0] != '{' && !bytes.Equal(trimmed, []byte("null")) {
+		return nil, typeError(path, "expected object")
+	}
+	temp := make(map[string]V)
+	err := json.Unmarshal(b, &temp)
+	if err != nil {
+		return nil, err
+	}
+	return temp, nil
+}
+
+// isObject reports whether the JSON value b is an object
+func isObject(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) > 0 && b[0] == '{'
+}
+
+// isArray reports whether the JSON value b is an array or null
+func isArray(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) > 0 && b[0] == '[' || bytes.Equal(b, []byte("null"))
+}
+
+// typeError returns the error for a value that does not have the expected JSON type
+func typeError(path string, format string, args ...any) error {
+	return &PathError{Path: path, Err: fmt.Errorf("%w: "+format, append([]any{ErrInvalidType}, args...)...)}
+}
+
+// invalidType reports a property that does not have the expected JSON type
+func invalidType(opts *decodeOptions, path string, expected string) {
+	opts.report(path, fmt.Errorf("%w: expected %s", ErrInvalidType, expected))
+}
@@ -0,0 +1,149 @@
+package haljson
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const malformedDocument = `{
+	"_links": {
+		"self": {"href": "/orders/1"},
+		"curies": [{"name": "ea", "href": "/docs/{rel}", "templated": "yes"}],
+		"ea:basket": {"href": "/baskets/1"},
+		"xx:unknown": [{"href": "/unknown"}],
+		"a/b": [{"href": 123}],
+		"nohref": {"title": "Missing"},
+		"find": {"href": "/orders{?id}", "templated": "yes"}
+	},
+	"_embedded": {
+		"ea:order": [
+			{"_links": {"self": {"href": "/orders/2"}}},
+			{"_links": {"self": {"title": 7}}}
+		]
+	},
+	"total": 10
+}`
+
+// pathErrors flattens errors joined by the Decoder into their PathErrors
+func pathErrors(t *testing.T, err error) map[string]error {
+	t.Helper()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !assert.True(t, ok, "expected joined errors, got %v", err) {
+		return nil
+	}
+	found := make(map[string]error)
+	for _, e := range joined.Unwrap() {
+		var pathErr *PathError
+		if assert.True(t, errors.As(e, &pathErr)) {
+			found[pathErr.Path] = pathErr.Err
+		}
+	}
+	return found
+}
+
+func TestDecoderLenientByDefault(t *testing.T) {
+	var r Resource[any]
+	err := NewDecoder(strings.NewReader(malformedDocument)).Decode(&r)
+	assert.Nil(t, err)
+	assert.Equal(t, "", r.Links.Relations["a/b"][0].Href)
+	assert.False(t, r.Links.Relations["find"][0].Templated)
+	assert.Equal(t, float64(10), r.Data["total"])
+}
+
+func TestDecoderStrict(t *testing.T) {
+	var r Resource[any]
+	err := NewDecoder(strings.NewReader(malformedDocument)).Strict().Decode(&r)
+	assert.NotNil(t, err)
+
+	found := pathErrors(t, err)
+	assert.Len(t, found, 7)
+	assert.ErrorIs(t, found["/_links/curies/0/templated"], ErrInvalidType)
+	assert.ErrorIs(t, found["/_links/xx:unknown"], ErrNoCurie)
+	assert.ErrorIs(t, found["/_links/a~1b/0/href"], ErrInvalidType)
+	assert.ErrorIs(t, found["/_links/nohref"], ErrMissingHref)
+	assert.ErrorIs(t, found["/_links/find/templated"], ErrInvalidType)
+	assert.ErrorIs(t, found["/_embedded/ea:order/1/_links/self"], ErrMissingHref)
+	assert.ErrorIs(t, found["/_embedded/ea:order/1/_links/self/title"], ErrInvalidType)
+
+	// The document is still decoded
+	assert.Equal(t, "/orders/1", r.Links.Self.Href)
+	assert.Len(t, r.Embeds.Relations["ea:order"], 2)
+	assert.True(t, errors.Is(err, ErrNoCurie))
+}
+
+func TestDecoderStrictValidDocument(t *testing.T) {
+	doc := `{"_links":{"self":{"href":"/"},"curies":[{"name":"ea","href":"/docs/{rel}","templated":true}],"ea:orders":[{"href":"/orders"}]},"total":1}`
+	var r Resource[any]
+	assert.Nil(t, NewDecoder(strings.NewReader(doc)).Strict().Decode(&r))
+	assert.Equal(t, "/orders", r.Links.Relations["ea:orders"][0].Href)
+}
+
+func TestDecoderStrictLinks(t *testing.T) {
+	var links Links
+	err := NewDecoder(strings.NewReader(`{"self":{"href":"/","name":false}}`)).Strict().Decode(&links)
+	found := pathErrors(t, err)
+	assert.ErrorIs(t, found["/self/name"], ErrInvalidType)
+	assert.Contains(t, err.Error(), "/self/name: invalid property type: expected string")
+}
+
+func TestDecoderNonHALValue(t *testing.T) {
+	var v map[string]int
+	assert.Nil(t, NewDecoder(strings.NewReader(`{"a":1}`)).Strict().Decode(&v))
+	assert.Equal(t, 1, v["a"])
+
+	var r Resource[any]
+	assert.NotNil(t, NewDecoder(strings.NewReader(`{"_links":`)).Decode(&r))
+}
+
+func TestUnmarshalNonObjectSections(t *testing.T) {
+	for _, doc := range []string{`{"_links": "/orders"}`, `{"_links": [{"href": "/"}]}`, `{"_links": 1}`} {
+		var r Resource[any]
+		err := json.Unmarshal([]byte(doc), &r)
+		assert.ErrorIs(t, err, ErrInvalidType, doc)
+		var pathErr *PathError
+		assert.True(t, errors.As(err, &pathErr), doc)
+		assert.Equal(t, "/_links", pathErr.Path, doc)
+	}
+
+	var r Resource[any]
+	err := json.Unmarshal([]byte(`{"_embedded": {"order": {"_links": true}}}`), &r)
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_embedded/order/_links", pathErr.Path)
+
+	err = json.Unmarshal([]byte(`{"_embedded": "none"}`), &r)
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_embedded", pathErr.Path)
+
+	// Relations and their items must have the expected types too
+	for doc, path := range map[string]string{
+		`{"_embedded": {"x": 3}}`:                  "/_embedded/x",
+		`{"_links": {"curies": "a"}}`:              "/_links/curies",
+		`{"_links": {"curies": [1]}}`:              "/_links/curies/0",
+		`{"_links": {"self": "/"}}`:                "/_links/self",
+		`{"_links": {"next": "/"}}`:                "/_links/next",
+		`{"_links": {"next": [{"href": "/"}, 1]}}`: "/_links/next/1",
+	} {
+		err = NewDecoder(strings.NewReader(doc)).Strict().Decode(&r)
+		assert.ErrorIs(t, err, ErrInvalidType, doc)
+		assert.True(t, errors.As(err, &pathErr), doc)
+		assert.Equal(t, path, pathErr.Path, doc)
+	}
+	var c Collection[map[string]any]
+	err = json.Unmarshal([]byte(`{"_embedded": {"item": 3}}`), &c)
+	assert.ErrorIs(t, err, ErrInvalidType)
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_embedded/item", pathErr.Path)
+
+	var s StateResource[map[string]any]
+	err = json.Unmarshal([]byte(`{"_links": "/orders"}`), &s)
+	assert.ErrorIs(t, err, ErrInvalidType)
+
+	// Null is an empty section
+	assert.Nil(t, json.Unmarshal([]byte(`{"_links": null, "_embedded": null, "a": 1}`), &r))
+	assert.Equal(t, float64(1), r.Data["a"])
+}
@@ -1,54 +1,419 @@
 package haljson
 
 import (
-	"bytes"
 	"encoding/json"
-	"fmt"
-	"strings"
+	"reflect"
 )
 
-// Embeds holds embedded relations by reltype
+// Embeds holds embedded relations by reltype. Each reltype is kept in
+// either Relations or Values, in the order its resources were added.
 type Embeds struct {
 	Relations map[string][]Resource[any]
+	// Values holds the reltypes embedding values of other types, such as
+	// Resource[T], StateResource[S] or structs with `hal` tags. The
+	// Resource[any] embedded under those reltypes are kept here as well,
+	// as *Resource[any].
+	Values map[string][]any
+	// cardinality records relations that are not represented as arrays
+	cardinality map[string]Cardinality
+	// order records relations in the order they were added
+	order []string
+}
+
+// SetCardinality sets whether reltype is represented as a single Resource Object or an array
+func (e *Embeds) SetCardinality(reltype string, cardinality Cardinality) {
+	if cardinality == Many {
+		delete(e.cardinality, reltype)
+		return
+	}
+	if e.cardinality == nil {
+		e.cardinality = make(map[string]Cardinality)
+	}
+	e.cardinality[reltype] = cardinality
+}
+
+// Cardinality returns how reltype is represented, relations default to Many
+func (e *Embeds) Cardinality(reltype string) Cardinality {
+	return e.cardinality[reltype]
+}
+
+// AddEmbed adds an embedded resource to reltype. A *Resource[any] is kept
+// in Relations unless reltype holds other values, any other value that
+// marshals as HAL is kept in Values.
+func (e *Embeds) AddEmbed(reltype string, embed any) error {
+	if isNil(embed) {
+		return ErrNilEmbed
+	}
+	_, mixed := e.Values[reltype]
+	switch v := embed.(type) {
+	case *Resource[any]:
+		if !mixed {
+			e.add(reltype, *v)
+			break
+		}
+		resource := *v
+		e.addValue(reltype, &resource)
+	case Resource[any]:
+		if !mixed {
+			e.add(reltype, v)
+			break
+		}
+		e.addValue(reltype, &v)
+	default:
+		e.addValue(reltype, embed)
+	}
+	if e.count(reltype) > 1 {
+		// A single relation can no longer be single once it grows
+		e.SetCardinality(reltype, Many)
+	}
+	return nil
+}
+
+// SetEmbed sets a single embedded resource for reltype, replacing any
+// existing embeds. The relation is represented as a single Resource Object
+// rather than an array.
+func (e *Embeds) SetEmbed(reltype string, embed any) error {
+	if isNil(embed) {
+		return ErrNilEmbed
+	}
+	delete(e.Relations, reltype)
+	delete(e.Values, reltype)
+	err := e.AddEmbed(reltype, embed)
+	if err != nil {
+		return err
+	}
+	e.SetCardinality(reltype, Single)
+	return nil
+}
+
+// Get returns the embedded resources of rel in the order they were added,
+// those kept in Relations as *Resource[any]
+func (e *Embeds) Get(rel string) []any {
+	var embeds []any
+	for i := range e.Relations[rel] {
+		embeds = append(embeds, &e.Relations[rel][i])
+	}
+	return append(embeds, e.Values[rel]...)
+}
+
+// First returns the first embedded resource of rel, or nil when there is none
+func (e *Embeds) First(rel string) any {
+	embeds := e.Get(rel)
+	if len(embeds) == 0 {
+		return nil
+	}
+	return embeds[0]
+}
+
+// ByName returns the embedded resource of rel whose self link has the
+// given name, or nil when there is none
+func (e *Embeds) ByName(rel string, name string) any {
+	for _, embed := range e.Get(rel) {
+		if node, ok := embed.(resourceNode); ok {
+			links := node.links()
+			if links != nil && links.Self != nil && links.Self.Name == name {
+				return embed
+			}
+		}
+	}
+	return nil
+}
+
+// Has reports whether rel holds any embedded resources
+func (e *Embeds) Has(rel string) bool {
+	return e.count(rel) > 0
+}
+
+// Remove removes every embedded resource of rel and reports whether there were any
+func (e *Embeds) Remove(rel string) bool {
+	if !e.rels()[rel] {
+		return false
+	}
+	delete(e.Relations, rel)
+	delete(e.Values, rel)
+	e.SetCardinality(rel, Many)
+	e.order = removeOrder(e.order, rel)
+	return true
+}
+
+// Replace replaces the embedded resources of rel with embeds, keeping its
+// position and cardinality. Replacing with no embeds removes the relation.
+func (e *Embeds) Replace(rel string, embeds ...any) error {
+	for _, embed := range embeds {
+		if isNil(embed) {
+			return ErrNilEmbed
+		}
+	}
+	if len(embeds) == 0 {
+		e.Remove(rel)
+		return nil
+	}
+	cardinality := e.Cardinality(rel)
+	delete(e.Relations, rel)
+	delete(e.Values, rel)
+	for _, embed := range embeds {
+		err := e.AddEmbed(rel, embed)
+		if err != nil {
+			return err
+		}
+	}
+	if len(embeds) == 1 {
+		e.SetCardinality(rel, cardinality)
+	}
+	return nil
+}
+
+// Rels returns the relations holding embedded resources in sorted order
+func (e *Embeds) Rels() []string {
+	return orderedKeys(e.rels(), nil, defaultEncodeOptions)
+}
+
+// Range calls fn for every embedded resource of every relation, in sorted
+// order of relations and as Get returns them. It stops when fn returns false.
+func (e *Embeds) Range(fn func(rel string, embed any) bool) {
+	for _, rel := range e.Rels() {
+		for _, embed := range e.Get(rel) {
+			if !fn(rel, embed) {
+				return
+			}
+		}
+	}
+}
+
+// add appends resources to reltype, recording insertion order
+func (e *Embeds) add(reltype string, resources ...Resource[any]) {
+	if _, ok := e.Relations[reltype]; !ok {
+		e.Relations[reltype] = []Resource[any]{}
+	}
+	e.Relations[reltype] = append(e.Relations[reltype], resources...)
+	e.order = appendOrder(e.order, reltype)
+}
+
+// addValue appends value to reltype, moving the resources reltype holds in
+// Relations to Values so that they keep their order
+func (e *Embeds) addValue(reltype string, value any) {
+	if e.Values == nil {
+		e.Values = make(map[string][]any)
+	}
+	e.Values[reltype] = append(e.Values[reltype], e.takeResources(reltype)...)
+	e.Values[reltype] = append(e.Values[reltype], value)
+	e.order = appendOrder(e.order, reltype)
+}
+
+// takeResources removes the resources of reltype from Relations and
+// returns them as *Resource[any]
+func (e *Embeds) takeResources(reltype string) []any {
+	resources, ok := e.Relations[reltype]
+	if !ok {
+		return nil
+	}
+	delete(e.Relations, reltype)
+	values := make([]any, len(resources))
+	for i := range resources {
+		values[i] = &resources[i]
+	}
+	return values
+}
+
+// count returns the number of embedded resources in reltype
+func (e *Embeds) count(reltype string) int {
+	return len(e.Relations[reltype]) + len(e.Values[reltype])
+}
+
+// rels returns every reltype held in Relations or Values
+func (e *Embeds) rels() map[string]bool {
+	rels := make(map[string]bool, len(e.Relations)+len(e.Values))
+	for rel := range e.Relations {
+		rels[rel] = true
+	}
+	for rel := range e.Values {
+		rels[rel] = true
+	}
+	return rels
+}
+
+// renameRel moves the embedded resources of rel to newRel, keeping its
+// position and cardinality. Resources already held by newRel are kept first.
+func (e *Embeds) renameRel(rel string, newRel string) {
+	if rel == newRel || !e.rels()[rel] {
+		return
+	}
+	cardinality := e.Cardinality(rel)
+	merged := e.rels()[newRel]
+	_, mixed := e.Values[rel]
+	_, mixedNew := e.Values[newRel]
+	if mixed || mixedNew {
+		values := append(e.takeResources(rel), e.Values[rel]...)
+		e.Values[newRel] = append(append(e.Values[newRel], e.takeResources(newRel)...), values...)
+		delete(e.Values, rel)
+	} else {
+		e.Relations[newRel] = append(e.Relations[newRel], e.Relations[rel]...)
+		delete(e.Relations, rel)
+	}
+	e.SetCardinality(rel, Many)
+	if merged {
+		e.SetCardinality(newRel, Many)
+	} else {
+		e.SetCardinality(newRel, cardinality)
+	}
+	e.order = renameOrder(e.order, rel, newRel, merged)
+}
+
+// embeds returns e, so that EmbedsOf accepts Embeds directly
+func (e *Embeds) embeds() *Embeds {
+	return e
 }
 
 // MarshalJSON marshals embeds
 func (e *Embeds) MarshalJSON() ([]byte, error) {
-	var bufferData []string
-	for key, links := range e.Relations {
-		jsonValue, err := json.Marshal(links)
+	return marshalPooled(e, defaultEncodeOptions)
+}
+
+// AppendJSON appends the encoding of e to dst and returns the extended
+// buffer, as MarshalJSON does without allocating a new one
+func (e *Embeds) AppendJSON(dst []byte) ([]byte, error) {
+	return e.appendHAL(dst, defaultEncodeOptions)
+}
+
+// appendHAL appends embeds to dst using the given options
+func (e *Embeds) appendHAL(dst []byte, opts *encodeOptions) ([]byte, error) {
+	dst = append(dst, '{')
+	var err error
+	var keys []string
+	if len(e.Values) == 0 {
+		keys = orderedKeys(e.Relations, e.order, opts)
+	} else {
+		keys = orderedKeys(e.rels(), e.order, opts)
+	}
+	for _, key := range keys {
+		dst, err = e.appendRel(dst, key, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// appendRel appends rel and its embedded resources to dst as an object member
+func (e *Embeds) appendRel(dst []byte, rel string, opts *encodeOptions) ([]byte, error) {
+	dst, err := appendKey(dst, rel)
+	if err != nil {
+		return nil, err
+	}
+	// A single relation is only written as an object while it holds exactly one resource
+	if e.Cardinality(rel) == Single && e.count(rel) == 1 {
+		return e.appendItems(dst, rel, opts)
+	}
+	dst, err = e.appendItems(append(dst, '['), rel, opts)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, ']'), nil
+}
+
+// appendItems appends the embedded resources of rel to dst, separated by
+// commas. rel is held by either Relations or Values.
+func (e *Embeds) appendItems(dst []byte, rel string, opts *encodeOptions) ([]byte, error) {
+	var err error
+	resources := e.Relations[rel]
+	for i := range resources {
+		dst, err = resources[i].appendHAL(appendSeparator(dst), opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, value := range e.Values[rel] {
+		dst, err = appendValue(appendSeparator(dst), value, opts)
 		if err != nil {
 			return nil, err
 		}
-		bufferData = append(bufferData, fmt.Sprintf("\"%s\": %s", key, string(jsonValue)))
 	}
-	buffer := bytes.NewBufferString("{")
-	buffer.WriteString(strings.Join(bufferData, ","))
-	buffer.WriteString("}")
-	return buffer.Bytes(), nil
+	return dst, nil
 }
 
 // UnmarshalJSON unmarshals embeds
 func (e *Embeds) UnmarshalJSON(b []byte) error {
-	temp := make(map[string]any)
-	err := json.Unmarshal(b, &temp)
+	return e.unmarshalHAL(b, newDecodeOptions(), "")
+}
+
+// unmarshalHAL unmarshals embeds found at path using the given options
+func (e *Embeds) unmarshalHAL(b []byte, opts *decodeOptions, path string) error {
+	_, err := e.unmarshalExcept(b, opts, path, "")
+	return err
+}
+
+// unmarshalExcept unmarshals embedded resources found at path as
+// unmarshalHAL does, leaving the relation except out. It returns the raw
+// value of except, nil when there is none.
+func (e *Embeds) unmarshalExcept(b []byte, opts *decodeOptions, path string, except string) (json.RawMessage, error) {
+	// Relations are kept raw and each resource is decoded from its own bytes
+	temp, err := unmarshalObject[json.RawMessage](b, path)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var excepted json.RawMessage
+	if except != "" {
+		excepted = temp[except]
+		delete(temp, except)
 	}
 	e.Relations = make(map[string][]Resource[any])
-	for k, v := range temp {
-		var res []Resource[any]
-		data, err := json.Marshal(v)
-		if err != nil {
-			return err
+	e.Values = nil
+	e.cardinality = nil
+	// Document order is not available here, record relations sorted
+	e.order = nil
+	for _, k := range orderedKeys(temp, nil, defaultEncodeOptions) {
+		e.order = append(e.order, k)
+		data := temp[k]
+		// A relation may be a single Resource Object rather than an array
+		if isObject(data) {
+			var res Resource[any]
+			err = res.unmarshalHAL(data, opts, pointer(path, k))
+			if err != nil {
+				return nil, err
+			}
+			e.Relations[k] = []Resource[any]{res}
+			e.SetCardinality(k, Single)
+			continue
+		}
+		var items []json.RawMessage
+		if !isArray(data) {
+			return nil, typeError(pointer(path, k), "expected object or array")
 		}
-		err = json.Unmarshal(data, &res)
+		err := json.Unmarshal(data, &items)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		res := make([]Resource[any], len(items))
+		for i, item := range items {
+			err = res[i].unmarshalHAL(item, opts, index(pointer(path, k), i))
+			if err != nil {
+				return nil, err
+			}
 		}
 		e.Relations[k] = res
 	}
-	return nil
+	return excepted, nil
+}
+
+// clone returns a copy of e that can be modified without affecting e
+func (e *Embeds) clone() *Embeds {
+	c := &Embeds{
+		Relations: make(map[string][]Resource[any], len(e.Relations)),
+		order:     append([]string(nil), e.order...),
+	}
+	for rel, resources := range e.Relations {
+		c.Relations[rel] = append([]Resource[any](nil), resources...)
+	}
+	for rel, values := range e.Values {
+		if c.Values == nil {
+			c.Values = make(map[string][]any, len(e.Values))
+		}
+		c.Values[rel] = append([]any(nil), values...)
+	}
+	for rel, cardinality := range e.cardinality {
+		c.SetCardinality(rel, cardinality)
+	}
+	return c
 }
 
 // NewEmbeds creates and initializes Embeds
@@ -57,3 +422,68 @@ func NewEmbeds() *Embeds {
 		Relations: make(map[string][]Resource[any]),
 	}
 }
+
+// embedsHolder is implemented by types holding Embeds
+type embedsHolder interface {
+	embeds() *Embeds
+}
+
+// EmbedsOf returns the resources embedded under rel as values of type E.
+// Values already of type E are returned as is, others, such as the
+// Resource[any] produced by decoding, are converted through their JSON
+// encoding with Unmarshal, so E may be a Resource[T], a StateResource[S] or
+// a struct with `hal` tags. holder is a *Resource[T], *StateResource[S] or *Embeds.
+func EmbedsOf[E any](holder embedsHolder, rel string) ([]E, error) {
+	e := holder.embeds()
+	if e == nil {
+		return nil, nil
+	}
+	var values []E
+	for i := range e.Relations[rel] {
+		value, err := convertEmbed[E](&e.Relations[rel][i])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	for _, embed := range e.Values[rel] {
+		value, err := convertEmbed[E](embed)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// convertEmbed converts an embedded value to E
+func convertEmbed[E any](embed any) (E, error) {
+	if value, ok := embed.(E); ok {
+		return value, nil
+	}
+	var value E
+	if rv := reflect.ValueOf(embed); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		if deref, ok := rv.Elem().Interface().(E); ok {
+			return deref, nil
+		}
+	}
+	b, err := marshalValue(embed, defaultEncodeOptions)
+	if err != nil {
+		return value, err
+	}
+	target := reflect.New(reflect.TypeOf(&value).Elem())
+	err = decodeInto(b, target.Elem(), newDecodeOptions(), "")
+	if err != nil {
+		return value, err
+	}
+	return target.Elem().Interface().(E), nil
+}
+
+// isNil reports whether v is nil or a nil pointer
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
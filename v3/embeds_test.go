@@ -54,3 +54,167 @@ func TestEmbedsUnmarshalErrors(t *testing.T) {
 	err := json.Unmarshal([]byte(`invalid json`), &embeds)
 	assert.NotNil(t, err)
 }
+
+func TestEmbedsSingleResource(t *testing.T) {
+	jsonData := `{"customer":{"_links":{"self":{"href":"/customers/1"}},"name":"Jane"},"orders":[{"total":10}]}`
+
+	var embeds Embeds
+	err := json.Unmarshal([]byte(jsonData), &embeds)
+	assert.Nil(t, err)
+	assert.Len(t, embeds.Relations["customer"], 1)
+	assert.Equal(t, "/customers/1", embeds.Relations["customer"][0].Links.Self.Href)
+	assert.Equal(t, "Jane", embeds.Relations["customer"][0].Data["name"])
+	assert.Equal(t, Single, embeds.Cardinality("customer"))
+	assert.Equal(t, Many, embeds.Cardinality("orders"))
+
+	var roundTrip map[string]any
+	b, err := json.Marshal(&embeds)
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(b, &roundTrip))
+	assert.IsType(t, map[string]any{}, roundTrip["customer"])
+	assert.IsType(t, []any{}, roundTrip["orders"])
+}
+
+func TestEmbedsSetCardinality(t *testing.T) {
+	embeds := NewEmbeds()
+	embeds.Relations["item"] = []Resource[any]{*NewResource[any]()}
+	embeds.SetCardinality("item", Single)
+	assert.Equal(t, Single, embeds.Cardinality("item"))
+
+	b, err := json.Marshal(embeds)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"item":{}}`, string(b))
+
+	embeds.SetCardinality("item", Many)
+	b, err = json.Marshal(embeds)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"item":[{}]}`, string(b))
+}
+
+func TestEmbedsTypedResources(t *testing.T) {
+	type Order struct {
+		Total    float64 `json:"total"`
+		Currency string  `json:"currency"`
+	}
+
+	r := NewResource[any]()
+	r.Self("/orders")
+
+	typed := NewResource[Order]()
+	typed.Self("/orders/1")
+	typed.Data["summary"] = Order{Total: 10, Currency: "USD"}
+	assert.Nil(t, r.AddEmbed("ea:order", typed))
+
+	state := NewStateResource(Order{Total: 20, Currency: "EUR"})
+	state.Self("/orders/2")
+	assert.Nil(t, r.AddEmbed("ea:order", state))
+
+	assert.Nil(t, r.AddEmbed("ea:customer", testCustomer{Self: "/customers/1", Name: "Jane"}))
+
+	untyped := NewResource[any]()
+	untyped.Self("/orders/0")
+	assert.Nil(t, r.AddEmbed("ea:order", untyped))
+
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders"}},"_embedded":{"ea:customer":[{"_links":{"self":{"href":"/customers/1"}},"name":"Jane"}],`+
+		`"ea:order":[{"_links":{"self":{"href":"/orders/1"}},"summary":{"total":10,"currency":"USD"}},`+
+		`{"_links":{"self":{"href":"/orders/2"}},"currency":"EUR","total":20},{"_links":{"self":{"href":"/orders/0"}}}]}}`, string(b))
+
+	// Values stored with their type are returned as is
+	states, err := EmbedsOf[*StateResource[Order]](r, "ea:order")
+	assert.Nil(t, err)
+	assert.Len(t, states, 3)
+	assert.Same(t, state, states[1])
+	assert.Equal(t, "/orders/0", states[2].Links.Self.Href)
+
+	// A round trip keeps the tree typed
+	var inflated Resource[any]
+	assert.Nil(t, json.Unmarshal(b, &inflated))
+	orders, err := EmbedsOf[StateResource[Order]](&inflated, "ea:order")
+	assert.Nil(t, err)
+	assert.Len(t, orders, 3)
+	assert.Equal(t, Order{Total: 20, Currency: "EUR"}, orders[1].State)
+	assert.Equal(t, "/orders/2", orders[1].Links.Self.Href)
+
+	// Properties that do not fit E are reported
+	_, err = EmbedsOf[*Resource[Order]](&inflated, "ea:order")
+	assert.NotNil(t, err)
+
+	customers, err := EmbedsOf[testCustomer](inflated.Embeds, "ea:customer")
+	assert.Nil(t, err)
+	assert.Equal(t, []testCustomer{{Self: "/customers/1", Name: "Jane"}}, customers)
+
+	missing, err := EmbedsOf[testCustomer](&inflated, "missing")
+	assert.Nil(t, err)
+	assert.Nil(t, missing)
+
+	_, err = EmbedsOf[int](&inflated, "ea:order")
+	assert.NotNil(t, err)
+}
+
+func TestEmbedsAddEmbed(t *testing.T) {
+	embeds := NewEmbeds()
+	assert.Equal(t, ErrNilEmbed, embeds.AddEmbed("item", nil))
+	assert.Equal(t, ErrNilEmbed, embeds.AddEmbed("item", (*Resource[any])(nil)))
+	assert.Equal(t, ErrNilEmbed, embeds.SetEmbed("item", (*Resource[int])(nil)))
+
+	assert.Nil(t, embeds.AddEmbed("item", *NewResource[any]()))
+	assert.Len(t, embeds.Relations["item"], 1)
+	assert.Nil(t, embeds.AddEmbed("item", map[string]any{"count": 1}))
+	// The relation moves to Values, keeping the order of its resources
+	assert.Empty(t, embeds.Relations["item"])
+	assert.Len(t, embeds.Values["item"], 2)
+	assert.Nil(t, embeds.AddEmbed("item", NewResource[any]()))
+	assert.Len(t, embeds.Values["item"], 3)
+	b, err := json.Marshal(embeds)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"item":[{},{"count":1},{}]}`, string(b))
+
+	assert.Nil(t, embeds.SetEmbed("item", NewResource[int]()))
+	assert.Empty(t, embeds.Relations["item"])
+	assert.Len(t, embeds.Values["item"], 1)
+	assert.Equal(t, Single, embeds.Cardinality("item"))
+
+	b, err = json.Marshal(embeds)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"item":{}}`, string(b))
+}
+
+func TestEmbedsNavigation(t *testing.T) {
+	first := NewResource[any]()
+	first.Links.Self = &Link{Href: "/orders/1", Name: "first"}
+	second := NewStateResource(map[string]int{"total": 2})
+	second.Links.Self = &Link{Href: "/orders/2", Name: "second"}
+
+	embeds := NewEmbeds()
+	embeds.AddEmbed("order", first)
+	embeds.AddEmbed("order", second)
+	embeds.SetEmbed("customer", map[string]any{"name": "Jane"})
+
+	assert.Equal(t, []string{"customer", "order"}, embeds.Rels())
+	assert.Len(t, embeds.Get("order"), 2)
+	assert.Equal(t, "/orders/1", embeds.First("order").(*Resource[any]).Links.Self.Href)
+	assert.Equal(t, second, embeds.ByName("order", "second"))
+	assert.Nil(t, embeds.ByName("order", "third"))
+	assert.Nil(t, embeds.First("missing"))
+	assert.True(t, embeds.Has("customer"))
+	assert.False(t, embeds.Has("missing"))
+
+	var rels []string
+	embeds.Range(func(rel string, embed any) bool {
+		rels = append(rels, rel)
+		return true
+	})
+	assert.Equal(t, []string{"customer", "order", "order"}, rels)
+
+	assert.Equal(t, ErrNilEmbed, embeds.Replace("customer", nil))
+	assert.Nil(t, embeds.Replace("customer", map[string]any{"name": "John"}))
+	assert.Equal(t, Single, embeds.Cardinality("customer"))
+	assert.True(t, embeds.Remove("order"))
+	assert.False(t, embeds.Remove("order"))
+
+	b, err := json.Marshal(embeds)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"customer":{"name":"John"}}`, string(b))
+}
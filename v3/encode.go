@@ -0,0 +1,466 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// KeyOrder controls the order in which relations and data keys are written
+type KeyOrder int
+
+const (
+	// SortedOrder writes relations and data keys alphabetically, this is the default
+	SortedOrder KeyOrder = iota
+	// InsertionOrder writes relations and data keys in the order they were added.
+	// Keys that were assigned to the underlying maps directly follow in sorted order.
+	InsertionOrder
+)
+
+// ReservedKeyPolicy controls how Data keys reserved by HAL, _links and
+// _embedded, are encoded
+type ReservedKeyPolicy int
+
+const (
+	// RejectReservedKeys fails with ErrReservedKey, this is the default
+	RejectReservedKeys ReservedKeyPolicy = iota
+	// MergeReservedKeys merges the members of a reserved Data key, which
+	// must be an object, into its section. Members of the section win over
+	// those of Data, the others follow in sorted order.
+	MergeReservedKeys
+)
+
+// encodeOptions carries Encoder settings through nested marshaling
+type encodeOptions struct {
+	order KeyOrder
+	// reserved decides what happens to Data keys reserved by HAL
+	reserved ReservedKeyPolicy
+	// hoist moves curies declared by embedded resources to the root
+	hoist bool
+	// root is the Links of the document being hoisted into
+	root *Links
+	// hoisted are the curies written by root in place of embedded resources
+	hoisted []Curie
+	// stream writes the streamed relations of the resource being encoded
+	stream *embedStream
+}
+
+// defaultEncodeOptions are the options used by MarshalJSON
+var defaultEncodeOptions = &encodeOptions{order: SortedOrder}
+
+// halEncoder is implemented by types that marshal differently depending on
+// encodeOptions. appendHAL appends the compact encoding to dst and returns
+// the extended buffer, so that a whole document is written into one buffer.
+type halEncoder interface {
+	appendHAL(dst []byte, opts *encodeOptions) ([]byte, error)
+}
+
+// bufferPool holds buffers reused by MarshalJSON and Encoder
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// maxPooledBuffer is the capacity above which buffers are not pooled, so
+// that one large document does not keep its memory alive
+const maxPooledBuffer = 64 << 10
+
+// getBuffer returns an empty buffer from bufferPool
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+// putBuffer returns b, the last contents of buffer, to bufferPool
+func putBuffer(buffer *[]byte, b []byte) {
+	if cap(b) > maxPooledBuffer {
+		return
+	}
+	*buffer = b[:0]
+	bufferPool.Put(buffer)
+}
+
+// marshalPooled encodes h in a pooled buffer and returns a copy of the result
+func marshalPooled(h halEncoder, opts *encodeOptions) ([]byte, error) {
+	buffer := getBuffer()
+	b, err := h.appendHAL(*buffer, opts)
+	if err != nil {
+		putBuffer(buffer, *buffer)
+		return nil, err
+	}
+	encoded := append([]byte(nil), b...)
+	putBuffer(buffer, b)
+	return encoded, nil
+}
+
+// Encoder writes HAL documents to an output stream
+type Encoder struct {
+	w      io.Writer
+	opts   encodeOptions
+	prefix string
+	indent string
+}
+
+// NewEncoder returns a new Encoder that writes to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetKeyOrder sets the order relations and data keys are written in
+func (enc *Encoder) SetKeyOrder(order KeyOrder) *Encoder {
+	enc.opts.order = order
+	return enc
+}
+
+// SetHoistCuries instructs the encoder to declare the curies of embedded
+// resources once, on the root resource. Curies with the same name but a
+// different definition elsewhere in the document are left where they are.
+func (enc *Encoder) SetHoistCuries(hoist bool) *Encoder {
+	enc.opts.hoist = hoist
+	return enc
+}
+
+// SetReservedKeys sets how Data keys reserved by HAL are encoded
+func (enc *Encoder) SetReservedKeys(policy ReservedKeyPolicy) *Encoder {
+	enc.opts.reserved = policy
+	return enc
+}
+
+// SetIndent instructs the encoder to indent output as json.MarshalIndent would
+func (enc *Encoder) SetIndent(prefix, indent string) *Encoder {
+	enc.prefix = prefix
+	enc.indent = indent
+	return enc
+}
+
+// Encode writes the HAL encoding of v followed by a newline. Values that
+// are not HAL types are encoded with encoding/json.
+func (enc *Encoder) Encode(v any) error {
+	return enc.encode(v, nil)
+}
+
+// encode writes v, streaming the relations of stream when it is not nil
+func (enc *Encoder) encode(v any, stream *embedStream) error {
+	opts := enc.opts
+	opts.stream = stream
+	if opts.hoist {
+		node, err := documentNode(v)
+		if err != nil {
+			return err
+		}
+		if node != nil && node.links() != nil {
+			v = node
+			opts.root = node.links()
+			opts.hoisted = hoistableCuries(node)
+		}
+	}
+	buffer := getBuffer()
+	b, err := appendValue(*buffer, v, &opts)
+	if err != nil {
+		putBuffer(buffer, *buffer)
+		return err
+	}
+	if enc.prefix != "" || enc.indent != "" {
+		var indented bytes.Buffer
+		err = json.Indent(&indented, b, enc.prefix, enc.indent)
+		if err != nil {
+			putBuffer(buffer, b)
+			return err
+		}
+		b = append(b[:0], indented.Bytes()...)
+	}
+	b = append(b, '\n')
+	_, err = enc.w.Write(b)
+	putBuffer(buffer, b)
+	return err
+}
+
+// marshalValue marshals v, passing opts down to HAL types and structs with `hal` tags
+func marshalValue(v any, opts *encodeOptions) ([]byte, error) {
+	return appendValue(nil, v, opts)
+}
+
+// appendValue appends the compact encoding of v to dst, passing opts down
+// to HAL types and structs with `hal` tags
+func appendValue(dst []byte, v any, opts *encodeOptions) ([]byte, error) {
+	switch value := v.(type) {
+	case halEncoder:
+		return value.appendHAL(dst, opts)
+	case nil, string, bool, float64, int, map[string]any, []any:
+		// Values decoded as any skip the reflection below
+		return appendJSON(dst, value)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Struct {
+		// Address the value so that pointer receiver marshalers such as Resource[T]'s are found
+		addressable := reflect.New(rv.Type())
+		addressable.Elem().Set(rv)
+		if h, ok := addressable.Interface().(halEncoder); ok {
+			return h.appendHAL(dst, opts)
+		}
+	}
+	if rv, ok := isHALStruct(rv); ok {
+		r, err := resourceFromStruct(rv, nil)
+		if err != nil {
+			return nil, err
+		}
+		return r.appendHAL(dst, opts)
+	}
+	return appendJSON(dst, v)
+}
+
+// appendJSON appends the encoding/json encoding of v to dst. The values
+// encoding/json decodes into any are written without marshaling them.
+func appendJSON(dst []byte, v any) ([]byte, error) {
+	var err error
+	switch value := v.(type) {
+	case nil:
+		return append(dst, "null"...), nil
+	case string:
+		return appendString(dst, value), nil
+	case bool:
+		return strconv.AppendBool(dst, value), nil
+	case int:
+		return strconv.AppendInt(dst, int64(value), 10), nil
+	case float64:
+		if !math.IsInf(value, 0) && !math.IsNaN(value) {
+			return appendFloat(dst, value), nil
+		}
+	case []any:
+		if value == nil {
+			return append(dst, "null"...), nil
+		}
+		dst = append(dst, '[')
+		for _, item := range value {
+			dst, err = appendJSON(appendSeparator(dst), item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, ']'), nil
+	case map[string]any:
+		if value == nil {
+			return append(dst, "null"...), nil
+		}
+		if validKeys(value) {
+			dst = append(dst, '{')
+			for _, key := range orderedKeys(value, nil, defaultEncodeOptions) {
+				dst, _ = appendKey(dst, key)
+				dst, err = appendJSON(dst, value[key])
+				if err != nil {
+					return nil, err
+				}
+			}
+			return append(dst, '}'), nil
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, b...), nil
+}
+
+// appendFloat appends f to dst formatted as encoding/json formats it
+func appendFloat(dst []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		if n := len(dst); n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+// validKeys reports whether every key of m is valid UTF-8. encoding/json
+// replaces invalid bytes rather than rejecting them, such maps are left to it.
+func validKeys(m map[string]any) bool {
+	for key := range m {
+		if !utf8.ValidString(key) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendString appends s to dst as a JSON string, escaped as encoding/json
+// escapes it
+func appendString(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c >= utf8.RuneSelf || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			// Only plain ASCII is written directly
+			b, _ := json.Marshal(s)
+			return append(dst, b...)
+		}
+	}
+	dst = append(dst, '"')
+	dst = append(dst, s...)
+	return append(dst, '"')
+}
+
+// appendSeparator appends a comma to dst unless it ends with the opening
+// of an object or array or with a member name, ready for the next member,
+// item or value
+func appendSeparator(dst []byte) []byte {
+	if n := len(dst); n > 0 && dst[n-1] != '{' && dst[n-1] != '[' && dst[n-1] != ':' {
+		dst = append(dst, ',')
+	}
+	return dst
+}
+
+// appendKey appends the separator and the name of an object member to dst.
+// Keys that are not valid UTF-8 are rejected, as quoteKey does.
+func appendKey(dst []byte, key string) ([]byte, error) {
+	if !utf8.ValidString(key) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	dst = appendString(appendSeparator(dst), key)
+	return append(dst, ':'), nil
+}
+
+// orderedKeys returns the keys of m in the order requested by opts. The
+// insertion order is taken from order, any remaining keys follow sorted.
+func orderedKeys[V any](m map[string]V, order []string, opts *encodeOptions) []string {
+	keys := make([]string, 0, len(m))
+	var seen map[string]bool
+	if opts.order == InsertionOrder {
+		seen = make(map[string]bool, len(order))
+		for _, k := range order {
+			if _, ok := m[k]; ok && !seen[k] {
+				keys = append(keys, k)
+				seen[k] = true
+			}
+		}
+	}
+	start := len(keys)
+	for k := range m {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys[start:])
+	return keys
+}
+
+// appendOrder records key in order if it is not already present
+func appendOrder(order []string, key string) []string {
+	for _, k := range order {
+		if k == key {
+			return order
+		}
+	}
+	return append(order, key)
+}
+
+// appendExtensions appends extension attributes, sorted by key, as members
+// of the JSON object being written to dst. Keys listed in reserved are
+// skipped.
+func appendExtensions(dst []byte, extensions map[string]any, reserved map[string]bool) ([]byte, error) {
+	if len(extensions) == 0 {
+		return dst, nil
+	}
+	var err error
+	for _, key := range orderedKeys(extensions, nil, defaultEncodeOptions) {
+		if reserved[key] {
+			continue
+		}
+		dst, err = appendKey(dst, key)
+		if err != nil {
+			return nil, err
+		}
+		dst, err = appendJSON(dst, extensions[key])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// removeOrder returns order without key
+func removeOrder(order []string, key string) []string {
+	var removed []string
+	for _, k := range order {
+		if k != key {
+			removed = append(removed, k)
+		}
+	}
+	return removed
+}
+
+// mergeObject adds the members of extra that object does not have to the
+// encoded JSON object, in sorted order. object may be nil.
+func mergeObject(object []byte, extra []byte) ([]byte, error) {
+	members, err := splitObject(object)
+	if err != nil {
+		return nil, err
+	}
+	extraMembers, err := splitObject(extra)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(members))
+	for _, m := range members {
+		seen[m.key] = true
+	}
+	sort.SliceStable(extraMembers, func(i, j int) bool {
+		return extraMembers[i].key < extraMembers[j].key
+	})
+	for _, m := range extraMembers {
+		if !seen[m.key] {
+			members = append(members, m)
+			seen[m.key] = true
+		}
+	}
+	merged := []byte{'{'}
+	for _, m := range members {
+		merged, err = appendKey(merged, m.key)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, m.value...)
+	}
+	return append(merged, '}'), nil
+}
+
+// renameOrder replaces key with newKey in order. When newKey is already
+// present, because two relations were merged, key is dropped instead.
+func renameOrder(order []string, key string, newKey string, merged bool) []string {
+	renamed := make([]string, 0, len(order))
+	for _, k := range order {
+		switch {
+		case k == key && !merged:
+			renamed = append(renamed, newKey)
+		case k != key:
+			renamed = append(renamed, k)
+		}
+	}
+	return renamed
+}
+
+// quoteKey escapes key for use as a JSON object member name. Keys that are
+// not valid UTF-8 cannot be represented in JSON without changing them, so
+// they are rejected rather than silently replaced.
+func quoteKey(key string) (string, error) {
+	if !utf8.ValidString(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,302 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var awkwardKeys = []string{
+	`quote"rel`,
+	`back\slash`,
+	"new\nline",
+	"tab\tand\x00null",
+	" line separator",
+	`"}, "injected": {"href": "/evil`,
+	"<html>&amp;",
+	"",
+}
+
+// roundTripKey marshals a resource using key as a link relation, embed
+// relation and data key and reports whether all three decode back to key
+func roundTripKey(key string) bool {
+	r := NewResource[any]()
+	r.Links.Relations[key] = []*Link{{Href: "/link"}}
+	r.Embeds.Relations[key] = []Resource[any]{*NewResource[any]()}
+	r.Data[key] = "value"
+
+	b, err := json.Marshal(r)
+	if err != nil || !json.Valid(b) {
+		return false
+	}
+
+	var decoded struct {
+		Links  map[string]any `json:"_links"`
+		Embeds map[string]any `json:"_embedded"`
+	}
+	var data map[string]any
+	if json.Unmarshal(b, &decoded) != nil || json.Unmarshal(b, &data) != nil {
+		return false
+	}
+	_, inLinks := decoded.Links[key]
+	_, inEmbeds := decoded.Embeds[key]
+	return inLinks && inEmbeds && data[key] != nil
+}
+
+func TestMarshalEscapesKeys(t *testing.T) {
+	for _, key := range awkwardKeys {
+		assert.True(t, roundTripKey(key), "key %q did not round trip", key)
+	}
+}
+
+func TestMarshalEscapesArbitraryKeys(t *testing.T) {
+	err := quick.Check(roundTripKey, &quick.Config{MaxCount: 500})
+	assert.Nil(t, err)
+}
+
+func TestMarshalKeysDecodeWithResource(t *testing.T) {
+	for _, key := range awkwardKeys {
+		r := NewResource[any]()
+		r.Links.Relations[key] = []*Link{{Href: "/link"}}
+		r.Data[key] = "value"
+
+		b, err := json.Marshal(r)
+		assert.Nil(t, err)
+
+		var inflated Resource[any]
+		assert.Nil(t, json.Unmarshal(b, &inflated), "key %q", key)
+		assert.Equal(t, "/link", inflated.Links.Relations[key][0].Href)
+		assert.Equal(t, "value", inflated.Data[key])
+	}
+}
+
+func TestMarshalRejectsInvalidUTF8Keys(t *testing.T) {
+	key := "bad\xffkey"
+
+	links := NewLinks()
+	links.Relations[key] = []*Link{{Href: "/"}}
+	_, err := json.Marshal(links)
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+
+	embeds := NewEmbeds()
+	embeds.Relations[key] = []Resource[any]{}
+	_, err = json.Marshal(embeds)
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+
+	r := NewResource[any]()
+	r.Data[key] = "value"
+	_, err = json.Marshal(r)
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+}
+
+func TestEmbedsMarshalDeterministic(t *testing.T) {
+	r := NewResource[any]()
+	for _, rel := range []string{"zeta", "alpha", "mu", "beta", "omega", "kappa"} {
+		r.AddEmbed(rel, NewResource[any]())
+	}
+
+	first, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_embedded":{"alpha":[{}],"beta":[{}],"kappa":[{}],"mu":[{}],"omega":[{}],"zeta":[{}]}}`, string(first))
+	for i := 0; i < 20; i++ {
+		b, err := json.Marshal(r)
+		assert.Nil(t, err)
+		assert.Equal(t, string(first), string(b))
+	}
+}
+
+func TestEncoderInsertionOrder(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/orders")
+	r.AddCurie(&Curie{Name: "ea", Href: "/docs/{rel}", Templated: true})
+	r.AddLink("next", &Link{Href: "/orders?page=2"})
+	r.AddLink("ea:admin", &Link{Href: "/admin"})
+	r.AddLink("find", &Link{Href: "/orders{?id}", Templated: true})
+	r.Links.Relations["added-directly"] = []*Link{{Href: "/direct"}}
+
+	order := NewResource[any]()
+	order.Set("total", 30)
+	order.Set("currency", "USD")
+	r.AddEmbed("ea:order", order)
+	r.AddEmbed("ea:basket", NewResource[any]())
+
+	r.Set("shippedToday", 20)
+	r.Set("currentlyProcessing", 14)
+	r.Data["added"] = true
+
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).SetKeyOrder(InsertionOrder).Encode(r)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders"},"curies":[{"name":"ea","href":"/docs/{rel}","templated":true}],`+
+		`"next":[{"href":"/orders?page=2"}],"ea:admin":[{"href":"/admin"}],"find":[{"href":"/orders{?id}","templated":true}],"added-directly":[{"href":"/direct"}]},`+
+		`"_embedded":{"ea:order":[{"total":30,"currency":"USD"}],"ea:basket":[{}]},`+
+		`"shippedToday":20,"currentlyProcessing":14,"added":true}`+"\n", buffer.String())
+
+	// The default order stays alphabetical
+	buffer.Reset()
+	err = NewEncoder(&buffer).Encode(r)
+	assert.Nil(t, err)
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b)+"\n", buffer.String())
+}
+
+func TestEncoderIndent(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/")
+	r.Set("b", 1)
+	r.Set("a", 2)
+
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).SetKeyOrder(InsertionOrder).SetIndent("", "  ").Encode(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"_links\": {\n    \"self\": {\n      \"href\": \"/\"\n    }\n  },\n  \"b\": 1,\n  \"a\": 2\n}\n", buffer.String())
+}
+
+func TestEncoderNonHALValue(t *testing.T) {
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).Encode(map[string]int{"b": 1, "a": 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":2,\"b\":1}\n", buffer.String())
+
+	err = NewEncoder(&buffer).Encode(make(chan int))
+	assert.NotNil(t, err)
+}
+
+func TestMarshalReservedDataKeys(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/orders")
+	r.Set(LINKS, map[string]any{"next": map[string]any{"href": "/orders?page=2"}})
+
+	_, err := json.Marshal(r)
+	assert.ErrorIs(t, err, ErrReservedKey)
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_links", pathErr.Path)
+
+	delete(r.Data, LINKS)
+	r.Set(EMBEDDED, []int{1})
+	_, err = json.Marshal(r)
+	assert.ErrorIs(t, err, ErrReservedKey)
+}
+
+func TestEncoderMergeReservedKeys(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/orders")
+	r.AddEmbed("order", NewResource[any]())
+	r.Set("total", 2)
+	r.Set(LINKS, map[string]any{
+		"self": map[string]any{"href": "/ignored"},
+		"next": map[string]any{"href": "/orders?page=2"},
+		"find": map[string]any{"href": "/orders{?id}", "templated": true},
+	})
+	r.Set(EMBEDDED, map[string]any{"order": []any{}, "extra": map[string]any{"id": 1}})
+
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).SetReservedKeys(MergeReservedKeys).Encode(r)
+	assert.Nil(t, err)
+	// Sections win, the members they lack follow sorted
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders"},"find":{"href":"/orders{?id}","templated":true},"next":{"href":"/orders?page=2"}},`+
+		`"_embedded":{"order":[{}],"extra":{"id":1}},"total":2}`+"\n", buffer.String())
+
+	// Reserved keys are merged even when the section is empty
+	s := NewResource[any]()
+	s.Set(LINKS, map[string]any{"self": map[string]any{"href": "/"}})
+	buffer.Reset()
+	err = NewEncoder(&buffer).SetReservedKeys(MergeReservedKeys).Encode(s)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/"}}}`+"\n", buffer.String())
+
+	// Only objects can be merged
+	s.Set(LINKS, "oops")
+	err = NewEncoder(&buffer).SetReservedKeys(MergeReservedKeys).Encode(s)
+	assert.ErrorIs(t, err, ErrReservedKey)
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_links", pathErr.Path)
+
+	// State resources follow the same policy
+	type Colliding struct {
+		Links map[string]any `json:"_links"`
+	}
+	state := NewStateResource(Colliding{Links: map[string]any{"up": map[string]any{"href": "/"}}})
+	buffer.Reset()
+	err = NewEncoder(&buffer).SetReservedKeys(MergeReservedKeys).Encode(state)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"up":{"href":"/"}}}`+"\n", buffer.String())
+}
+
+func TestAppendJSONMatchesMarshal(t *testing.T) {
+	values := []any{
+		nil, true, false, 0, -42, "", "plain", `quote"back\slash`, "<html>&amp;", "naïve ☃", "new\nline\x00", "\xff",
+		0.0, 1.5, -2.25, 1e20, 1e21, 1e-6, 1e-7, 123456789.123, -1e-300, 5e-324,
+		[]any{}, []any{1.0, "a", nil, map[string]any{"b": 2.0, "a": []any{true}}},
+		map[string]any{}, map[string]any{"z": 1.0, "a": "x", "<": ">"}, map[string]any{"\xff": 1.0},
+		[]any(nil), map[string]any(nil), []int{1, 2}, struct{ A int }{1},
+	}
+	for _, v := range values {
+		expected, err := json.Marshal(v)
+		assert.NoError(t, err)
+		b, err := appendJSON([]byte("prefix"), v)
+		assert.NoError(t, err)
+		assert.Equal(t, "prefix"+string(expected), string(b), "%#v", v)
+	}
+
+	float := func(f float64) bool {
+		expected, _ := json.Marshal(f)
+		b, _ := appendJSON(nil, f)
+		return string(expected) == string(b)
+	}
+	assert.NoError(t, quick.Check(float, nil))
+	str := func(s string) bool {
+		expected, _ := json.Marshal(s)
+		return string(expected) == string(appendString(nil, s))
+	}
+	assert.NoError(t, quick.Check(str, nil))
+}
+
+func TestAppendJSON(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/orders")
+	r.AddCurie(&Curie{Name: "ea", Href: "/docs/{rel}", Templated: true})
+	r.AddLink("ea:find", (&Link{Href: "/orders{?id}", Templated: true}).SetExtension("x-rate", 10), Single)
+	order := NewStateResource(map[string]any{"total": 30.5, "currency": "USD"})
+	order.Self("/orders/1")
+	r.AddEmbed("ea:order", order)
+	r.Set("count", 1)
+	r.Set("tags", []string{"a", "<b>"})
+
+	expected, err := json.Marshal(r)
+	assert.NoError(t, err)
+	b, err := r.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(b))
+
+	// The encoding is appended to what dst holds
+	b, err = r.AppendJSON([]byte("prefix:"))
+	assert.NoError(t, err)
+	assert.Equal(t, "prefix:"+string(expected), string(b))
+
+	for _, v := range []interface {
+		json.Marshaler
+		AppendJSON([]byte) ([]byte, error)
+	}{r.Links, r.Embeds, order, NewCollection("item", []int{1, 2})} {
+		expected, err := json.Marshal(v)
+		assert.NoError(t, err)
+		b, err := v.AppendJSON([]byte("prefix:"))
+		assert.NoError(t, err)
+		assert.Equal(t, "prefix:"+string(expected), string(b))
+	}
+
+	// Errors leave no partial encoding behind
+	r.Set("bad", make(chan int))
+	b, err = r.AppendJSON([]byte("prefix:"))
+	assert.NotNil(t, err)
+	assert.Nil(t, b)
+	_, err = r.MarshalJSON()
+	assert.NotNil(t, err)
+}
@@ -3,6 +3,44 @@ package haljson
 import "errors"
 
 var (
-	// ErrNoCurie is returned when a curied link was added without the associated curie
+	// ErrNoCurie is reported when a curied link has no associated curie in scope
 	ErrNoCurie = errors.New("must add curie before adding a curied link")
+	// ErrCardinality is returned when a relation holding several values was forced to be single
+	ErrCardinality = errors.New("relation with more than one value cannot be single")
+	// ErrInvalidKey is returned when a relation or data key cannot be represented in JSON
+	ErrInvalidKey = errors.New("key is not valid UTF-8")
+	// ErrInvalidType is reported in strict mode when a property has the wrong JSON type
+	ErrInvalidType = errors.New("invalid property type")
+	// ErrMissingHref is reported in strict mode when a link has no href
+	ErrMissingHref = errors.New("link is missing href")
+	// ErrInvalidTag is returned when a `hal` struct tag cannot be applied to its field
+	ErrInvalidTag = errors.New("invalid hal struct tag")
+	// ErrReservedKey is returned when state uses a key reserved by HAL, such as _links or _embedded
+	ErrReservedKey = errors.New("state uses a reserved key")
+	// ErrNilEmbed is returned when a nil value is embedded
+	ErrNilEmbed = errors.New("cannot embed a nil resource")
+	// ErrNilLink is returned when a nil link is added
+	ErrNilLink = errors.New("cannot add a nil link")
+	// ErrNotTemplated is returned when expanding a link or curie that is not templated
+	ErrNotTemplated = errors.New("href is not templated")
+	// ErrInvalidTemplate is returned when a URI Template is malformed
+	ErrInvalidTemplate = errors.New("invalid URI template")
+	// ErrUnregisteredRel is returned when a relation type is not in the Registry in scope
+	ErrUnregisteredRel = errors.New("link relation type is not registered")
+	// ErrInvalidRel is returned when a relation type is not a valid CURIE or absolute URI
+	ErrInvalidRel = errors.New("invalid link relation type")
+	// ErrInvalidPaging is returned when a page, size, offset or limit is out of range
+	ErrInvalidPaging = errors.New("invalid paging")
+	// ErrMissingCurieName is reported by Validate for a curie without a name
+	ErrMissingCurieName = errors.New("curie is missing name")
+	// ErrMissingRelVariable is reported by Validate for a templated curie href without {rel}
+	ErrMissingRelVariable = errors.New("curie href is missing the rel variable")
+	// ErrDuplicateCurie is reported by Validate when a resource declares a curie name twice
+	ErrDuplicateCurie = errors.New("duplicate curie name")
+	// ErrDuplicateName is reported by Validate when links of one relation share a name
+	ErrDuplicateName = errors.New("duplicate link name within relation")
+	// ErrStreamIndent is returned by Encoder.EncodeStream when indentation is set
+	ErrStreamIndent = errors.New("cannot indent a streamed document")
+	// ErrNotResource is returned by Encoder.EncodeStream for values that are not HAL resources
+	ErrNotResource = errors.New("value is not a HAL resource")
 )
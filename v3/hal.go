@@ -1,3 +1,9 @@
+// Package haljson encodes and decodes HAL+JSON documents.
+//
+// Resources are built with NewValidatedResource or NewValidatedStateResource,
+// whose links only accept registered relation types, absolute URIs and
+// CURIEs. NewResource and NewStateResource accept any relation type, as
+// decoding does.
 package haljson
 
 const (
@@ -26,3 +32,13 @@ const (
 	// EMBEDDED represents the _embedded key
 	EMBEDDED = "_embedded"
 )
+
+// Cardinality describes whether a relation is represented by a single object or an array
+type Cardinality int
+
+const (
+	// Many represents a relation as an array of objects, this is the default
+	Many Cardinality = iota
+	// Single represents a relation as a single object
+	Single
+)
@@ -1,10 +1,7 @@
 package haljson
 
 import (
-	"bytes"
 	"encoding/json"
-	"fmt"
-	"sort"
 	"strings"
 )
 
@@ -18,6 +15,20 @@ type Link struct {
 	Templated   bool   `json:"templated,omitempty"`
 	Title       string `json:"title,omitempty"`
 	Type        string `json:"type,omitempty"`
+	// Extensions holds attributes beyond those defined by HAL, keyed by name
+	Extensions map[string]any `json:"-"`
+}
+
+// linkProperties are the Link Object properties defined by HAL
+var linkProperties = map[string]bool{
+	DEPRECATION: true,
+	HREF:        true,
+	HREFLANG:    true,
+	NAME:        true,
+	PROFILE:     true,
+	TEMPLATED:   true,
+	TITLE:       true,
+	TYPE:        true,
 }
 
 // Links is a container of Link, mapped by relation, and contains Curies
@@ -26,6 +37,14 @@ type Links struct {
 	Curies []Curie `json:"curies,omitempty"`
 	// When serializing to JSON we need to handle this specially
 	Relations map[string][]*Link
+	// cardinality records relations that are not represented as arrays
+	cardinality map[string]Cardinality
+	// order records relations in the order they were added
+	order []string
+	// parent is the Links of the resource embedding this one, whose curies are inherited
+	parent *Links
+	// registry restricts the relation types AddLink accepts, when set
+	registry *Registry
 }
 
 // SetTitle sets the title, chainable
@@ -76,6 +95,70 @@ func (l *Link) SetName(name string) *Link {
 	return l
 }
 
+// SetExtension sets an extension attribute, chainable. Keys naming a HAL
+// defined property are ignored when marshaling.
+func (l *Link) SetExtension(key string, value any) *Link {
+	if l.Extensions == nil {
+		l.Extensions = make(map[string]any)
+	}
+	l.Extensions[key] = value
+	return l
+}
+
+// Extension returns an extension attribute and whether it was set
+func (l *Link) Extension(key string) (any, bool) {
+	value, ok := l.Extensions[key]
+	return value, ok
+}
+
+// MarshalJSON marshals a link with its extension attributes
+func (l Link) MarshalJSON() ([]byte, error) {
+	return l.appendJSON(nil)
+}
+
+// appendJSON appends the encoding of l to dst, properties in the order of
+// the Link fields followed by the extension attributes
+func (l *Link) appendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	dst = appendProperty(dst, DEPRECATION, l.Deprecation)
+	dst = appendProperty(dst, HREF, l.Href)
+	dst = appendProperty(dst, HREFLANG, l.HrefLang)
+	dst = appendProperty(dst, NAME, l.Name)
+	dst = appendProperty(dst, PROFILE, l.Profile)
+	if l.Templated {
+		dst = append(appendSeparator(dst), `"templated":true`...)
+	}
+	dst = appendProperty(dst, TITLE, l.Title)
+	dst = appendProperty(dst, TYPE, l.Type)
+	dst, err := appendExtensions(dst, l.Extensions, linkProperties)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, '}'), nil
+}
+
+// appendProperty appends a string property of a link or curie to dst,
+// leaving it out when empty
+func appendProperty(dst []byte, key string, value string) []byte {
+	if value == "" {
+		return dst
+	}
+	// HAL property names are plain ASCII and cannot fail
+	dst, _ = appendKey(dst, key)
+	return appendString(dst, value)
+}
+
+// UnmarshalJSON unmarshals a link, keeping unknown attributes as extensions
+func (l *Link) UnmarshalJSON(b []byte) error {
+	var properties map[string]any
+	err := json.Unmarshal(b, &properties)
+	if err != nil {
+		return err
+	}
+	*l = linkFromProperties(properties, newDecodeOptions(), "")
+	return nil
+}
+
 // AddCurie adds a curie to the links
 func (l *Links) AddCurie(curie *Curie) error {
 	if l.Curies == nil {
@@ -85,77 +168,164 @@ func (l *Links) AddCurie(curie *Curie) error {
 	return nil
 }
 
-// AddLink adds a link to reltype
-func (l *Links) AddLink(reltype string, link *Link) error {
-	// Check if curied and that if curied, curie exists
-	// Note: we check > 0 to exclude relation types starting with ":"
-	if strings.Index(reltype, ":") > 0 {
-		parts := strings.Split(reltype, ":")
-		if l.Curies == nil {
-			return ErrNoCurie
-		}
-		curieExists := false
-		for _, curie := range l.Curies {
-			if parts[0] == curie.Name {
-				curieExists = true
-				break
-			}
-		}
-		if !curieExists {
-			return ErrNoCurie
-		}
+// AddLink adds a link to reltype. An optional Cardinality forces the relation
+// to be represented as a single Link Object or as an array of Link Objects.
+// The curie of a CURIE reltype is not checked, as it may be declared by a
+// resource that embeds l's resource later on. Validate reports CURIE
+// relations whose curie is not in scope with ErrNoCurie.
+func (l *Links) AddLink(reltype string, link *Link, cardinality ...Cardinality) error {
+	err := l.checkLink(reltype, link)
+	if err != nil {
+		return err
+	}
+	return l.addLink(reltype, link, cardinality...)
+}
+
+// addLink adds a link that has been checked to reltype
+func (l *Links) addLink(reltype string, link *Link, cardinality ...Cardinality) error {
+	if len(cardinality) > 0 && cardinality[0] == Single && len(l.Relations[reltype]) > 0 {
+		return ErrCardinality
 	}
 	if _, ok := l.Relations[reltype]; !ok {
 		l.Relations[reltype] = []*Link{}
 	}
 
 	l.Relations[reltype] = append(l.Relations[reltype], link)
+	l.order = appendOrder(l.order, reltype)
+	switch {
+	case len(cardinality) > 0:
+		l.SetCardinality(reltype, cardinality[0])
+	case len(l.Relations[reltype]) > 1:
+		// A single relation can no longer be single once it grows
+		l.SetCardinality(reltype, Many)
+	}
 	return nil
 }
 
+// checkLink reports whether link may be added to reltype
+func (l *Links) checkLink(reltype string, link *Link) error {
+	if link == nil {
+		return ErrNilLink
+	}
+	if registry := l.registryScope(); registry != nil {
+		err := registry.Validate(reltype)
+		if err != nil {
+			return err
+		}
+	}
+	if link.Templated {
+		err := ValidateURITemplate(link.Href)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCardinality sets whether reltype is represented as a single Link Object or an array
+func (l *Links) SetCardinality(reltype string, cardinality Cardinality) {
+	if cardinality == Many {
+		delete(l.cardinality, reltype)
+		return
+	}
+	if l.cardinality == nil {
+		l.cardinality = make(map[string]Cardinality)
+	}
+	l.cardinality[reltype] = cardinality
+}
+
+// Cardinality returns how reltype is represented, relations default to Many
+func (l *Links) Cardinality(reltype string) Cardinality {
+	return l.cardinality[reltype]
+}
+
 // MarshalJSON to marshal Links properly
 func (l *Links) MarshalJSON() ([]byte, error) {
-	var bufferData []string
+	return marshalPooled(l, defaultEncodeOptions)
+}
+
+// AppendJSON appends the encoding of l to dst and returns the extended
+// buffer, as MarshalJSON does without allocating a new one
+func (l *Links) AppendJSON(dst []byte) ([]byte, error) {
+	return l.appendHAL(dst, defaultEncodeOptions)
+}
+
+// appendHAL appends Links to dst using the given options
+func (l *Links) appendHAL(dst []byte, opts *encodeOptions) ([]byte, error) {
+	dst = append(dst, '{')
+	var err error
 	if l.Self != nil {
-		jsonValue, err := json.Marshal(l.Self)
+		dst, _ = appendKey(dst, SELF)
+		dst, err = l.Self.appendJSON(dst)
 		if err != nil {
 			return nil, err
 		}
-		bufferData = append(bufferData, fmt.Sprintf("\"%s\": %s", SELF, string(jsonValue)))
 	}
-	if l.Curies != nil && len(l.Curies) > 0 {
-		jsonValue, err := json.Marshal(l.Curies)
+	curies := l.declaredCuries(opts)
+	if len(curies) > 0 {
+		dst, _ = appendKey(dst, CURIES)
+		dst = append(dst, '[')
+		for i := range curies {
+			dst, err = curies[i].appendJSON(appendSeparator(dst))
+			if err != nil {
+				return nil, err
+			}
+		}
+		dst = append(dst, ']')
+	}
+
+	// Order keys for deterministic output (required for consistent testing and comparison)
+	for _, key := range orderedKeys(l.Relations, l.order, opts) {
+		dst, err = appendKey(dst, key)
+		if err != nil {
+			return nil, err
+		}
+		links := l.Relations[key]
+		// A single relation is only written as an object while it holds exactly one link
+		if l.Cardinality(key) == Single && len(links) == 1 {
+			dst, err = appendLink(dst, links[0])
+		} else {
+			dst, err = appendLinks(dst, links)
+		}
 		if err != nil {
 			return nil, err
 		}
-		bufferData = append(bufferData, fmt.Sprintf("\"%s\": %s", CURIES, string(jsonValue)))
 	}
+	return append(dst, '}'), nil
+}
 
-	// Sort keys for deterministic output (required for consistent testing and comparison)
-	sortedKeys := make([]string, 0, len(l.Relations))
-	for k := range l.Relations {
-		sortedKeys = append(sortedKeys, k)
+// appendLink appends link to dst, null when it is nil
+func appendLink(dst []byte, link *Link) ([]byte, error) {
+	if link == nil {
+		return append(dst, "null"...), nil
 	}
-	sort.Strings(sortedKeys)
+	return link.appendJSON(dst)
+}
 
-	for _, key := range sortedKeys {
-		jsonValue, err := json.Marshal(l.Relations[key])
+// appendLinks appends links to dst as an array, null when it is nil
+func appendLinks(dst []byte, links []*Link) ([]byte, error) {
+	if links == nil {
+		return append(dst, "null"...), nil
+	}
+	dst = append(dst, '[')
+	var err error
+	for _, link := range links {
+		dst, err = appendLink(appendSeparator(dst), link)
 		if err != nil {
 			return nil, err
 		}
-		bufferData = append(bufferData, fmt.Sprintf("\"%s\": %s", key, string(jsonValue)))
 	}
-	joined := strings.Join(bufferData, ",")
-	buffer := bytes.NewBufferString("{")
-	buffer.WriteString(joined)
-	buffer.WriteString("}")
-	return buffer.Bytes(), nil
+	return append(dst, ']'), nil
 }
 
 // UnmarshalJSON to unmarshal links
 func (l *Links) UnmarshalJSON(b []byte) error {
-	temp := make(map[string]any)
-	err := json.Unmarshal(b, &temp)
+	return l.unmarshalHAL(b, newDecodeOptions(), "")
+}
+
+// unmarshalHAL unmarshals links found at path using the given options
+func (l *Links) unmarshalHAL(b []byte, opts *decodeOptions, path string) error {
+	temp, err := unmarshalObject[any](b, path)
 	if err != nil {
 		return err
 	}
@@ -163,30 +333,14 @@ func (l *Links) UnmarshalJSON(b []byte) error {
 		var mycuries []Curie
 		curiesArray, ok := temp[CURIES].([]any)
 		if !ok {
-			return fmt.Errorf("invalid curies format: expected array")
+			return typeError(pointer(path, CURIES), "invalid curies format: expected array")
 		}
-		for _, curiesItem := range curiesArray {
+		for i, curiesItem := range curiesArray {
 			curiesMap, ok := curiesItem.(map[string]any)
 			if !ok {
-				return fmt.Errorf("invalid curie format: expected object")
-			}
-			var curie Curie
-			for k, v := range curiesMap {
-				switch k {
-				case NAME:
-					if name, ok := v.(string); ok {
-						curie.Name = name
-					}
-				case HREF:
-					if href, ok := v.(string); ok {
-						curie.Href = href
-					}
-				case TEMPLATED:
-					if templated, ok := v.(bool); ok {
-						curie.Templated = templated
-					}
-				}
+				return typeError(index(pointer(path, CURIES), i), "invalid curie format: expected object")
 			}
+			curie := curieFromProperties(curiesMap, opts, index(pointer(path, CURIES), i))
 			mycuries = append(mycuries, curie)
 		}
 		l.Curies = mycuries
@@ -197,105 +351,353 @@ func (l *Links) UnmarshalJSON(b []byte) error {
 	if _, ok := temp[SELF]; ok {
 		selfMap, ok := temp[SELF].(map[string]any)
 		if !ok {
-			return fmt.Errorf("invalid self link format: expected object")
+			return typeError(pointer(path, SELF), "invalid self link format: expected object")
 		}
 		// Handle all Link fields for consistency with regular link unmarshaling
-		for k, v := range selfMap {
-			switch k {
-			case HREF:
-				if href, ok := v.(string); ok {
-					self.Href = href
-				}
-			case DEPRECATION:
-				if deprecation, ok := v.(string); ok {
-					self.Deprecation = deprecation
-				}
-			case HREFLANG:
-				if hreflang, ok := v.(string); ok {
-					self.HrefLang = hreflang
-				}
-			case NAME:
-				if name, ok := v.(string); ok {
-					self.Name = name
-				}
-			case PROFILE:
-				if profile, ok := v.(string); ok {
-					self.Profile = profile
-				}
-			case TITLE:
-				if title, ok := v.(string); ok {
-					self.Title = title
-				}
-			case TYPE:
-				if typeval, ok := v.(string); ok {
-					self.Type = typeval
-				}
-			case TEMPLATED:
-				if templated, ok := v.(bool); ok {
-					self.Templated = templated
-				}
-			}
-		}
+		self = linkFromProperties(selfMap, opts, pointer(path, SELF))
 		l.Self = &self
 		delete(temp, SELF)
 	}
 
 	l.Relations = make(map[string][]*Link)
-	for rel, v := range temp {
+	l.cardinality = nil
+	// Document order is not available here, record relations sorted
+	l.order = nil
+	for _, rel := range orderedKeys(temp, nil, defaultEncodeOptions) {
+		l.order = append(l.order, rel)
+		v := temp[rel]
+		// A relation may be a single Link Object rather than an array
+		if properties, ok := v.(map[string]any); ok {
+			link := linkFromProperties(properties, opts, pointer(path, rel))
+			l.Relations[rel] = []*Link{&link}
+			l.SetCardinality(rel, Single)
+			continue
+		}
 		linksArray, ok := v.([]any)
 		if !ok {
-			return fmt.Errorf("invalid links format for relation %q: expected array", rel)
+			return typeError(pointer(path, rel), "invalid links format for relation %q: expected object or array", rel)
 		}
 		var links []*Link
-		for _, linkItem := range linksArray {
+		for i, linkItem := range linksArray {
 			properties, ok := linkItem.(map[string]any)
 			if !ok {
-				return fmt.Errorf("invalid link format: expected object")
-			}
-			var link Link
-			for key, property := range properties {
-				switch key {
-				case HREF:
-					if href, ok := property.(string); ok {
-						link.Href = href
-					}
-				case DEPRECATION:
-					if deprecation, ok := property.(string); ok {
-						link.Deprecation = deprecation
-					}
-				case HREFLANG:
-					if hreflang, ok := property.(string); ok {
-						link.HrefLang = hreflang
-					}
-				case NAME:
-					if name, ok := property.(string); ok {
-						link.Name = name
-					}
-				case PROFILE:
-					if profile, ok := property.(string); ok {
-						link.Profile = profile
-					}
-				case TITLE:
-					if title, ok := property.(string); ok {
-						link.Title = title
-					}
-				case TYPE:
-					if typeval, ok := property.(string); ok {
-						link.Type = typeval
-					}
-				case TEMPLATED:
-					if templated, ok := property.(bool); ok {
-						link.Templated = templated
-					}
-				}
+				return typeError(index(pointer(path, rel), i), "invalid link format: expected object")
 			}
+			link := linkFromProperties(properties, opts, index(pointer(path, rel), i))
 			links = append(links, &link)
 		}
 		l.Relations[rel] = links
 	}
+
+	// Curied relations must have a matching curie, as AddLink requires
+	for _, rel := range l.order {
+		if prefix, ok := curiePrefix(rel); ok && !l.hasCurie(prefix) {
+			opts.report(pointer(path, rel), ErrNoCurie)
+		}
+	}
+	return nil
+}
+
+// SetRegistry restricts the relation types AddLink accepts to those that are
+// registered in registry, absolute URIs and CURIEs. Links of embedded
+// resources use the registry of the resources embedding them unless they
+// set their own. A nil registry accepts any relation type.
+func (l *Links) SetRegistry(registry *Registry) {
+	l.registry = registry
+}
+
+// registryScope returns the Registry in scope for l, or nil
+func (l *Links) registryScope() *Registry {
+	for links := l; links != nil; links = links.parent {
+		if links.registry != nil {
+			return links.registry
+		}
+	}
+	return nil
+}
+
+// declaredCuries returns the curies l writes with opts
+func (l *Links) declaredCuries(opts *encodeOptions) []Curie {
+	if opts.hoist {
+		return opts.curiesFor(l)
+	}
+	return l.Curies
+}
+
+// isEmpty reports whether l writes no links or curies with opts
+func (l *Links) isEmpty(opts *encodeOptions) bool {
+	return l.Self == nil && len(l.Relations) == 0 && len(l.declaredCuries(opts)) == 0
+}
+
+// hasCurie reports whether a curie named name is in scope
+func (l *Links) hasCurie(name string) bool {
+	return hasCurie(l.curieScope(), name)
+}
+
+// hasCurie reports whether scope holds a curie named name
+func hasCurie(scope []Curie, name string) bool {
+	for _, curie := range scope {
+		if curie.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// curiePrefix returns the prefix of rel when it is a CURIE
+func curiePrefix(rel string) (string, bool) {
+	// Relation types starting with ":" and absolute URIs are not CURIEs
+	prefix, _, ok := strings.Cut(rel, ":")
+	return prefix, ok && prefix != "" && !isAbsoluteRel(rel)
+}
+
+// curieScope returns the curies in scope for l, its own followed by those
+// inherited from the resources embedding it, nearest first
+func (l *Links) curieScope() []Curie {
+	var scope []Curie
+	for links := l; links != nil; links = links.parent {
+		scope = append(scope, links.Curies...)
+	}
+	return scope
+}
+
+// linkFromProperties builds a Link from decoded JSON properties found at
+// path. HAL properties of the wrong type are ignored, or reported in strict
+// mode, and unknown ones are kept as extensions.
+func linkFromProperties(properties map[string]any, opts *decodeOptions, path string) Link {
+	var link Link
+	for key, property := range properties {
+		if key == TEMPLATED {
+			if templated, ok := property.(bool); ok {
+				link.Templated = templated
+			} else {
+				invalidType(opts, pointer(path, key), "boolean")
+			}
+			continue
+		}
+		if !linkProperties[key] {
+			link.SetExtension(key, property)
+			continue
+		}
+		value, ok := property.(string)
+		if !ok {
+			invalidType(opts, pointer(path, key), "string")
+			continue
+		}
+		switch key {
+		case HREF:
+			link.Href = value
+		case DEPRECATION:
+			link.Deprecation = value
+		case HREFLANG:
+			link.HrefLang = value
+		case NAME:
+			link.Name = value
+		case PROFILE:
+			link.Profile = value
+		case TITLE:
+			link.Title = value
+		case TYPE:
+			link.Type = value
+		}
+	}
+	if _, ok := properties[HREF]; !ok {
+		opts.report(path, ErrMissingHref)
+	}
+	return link
+}
+
+// ExpandRel expands a CURIE such as ea:orders into the full URI of the
+// relation using the curies in scope. Other relations are returned unchanged.
+func (l *Links) ExpandRel(rel string) string {
+	return expandRel(l.curieScope(), rel)
+}
+
+// CompactRel compacts the full URI of a relation into a CURIE using the
+// curies in scope. URIs that match no curie are returned unchanged.
+func (l *Links) CompactRel(uri string) string {
+	return compactRel(l.curieScope(), uri)
+}
+
+// Get returns the links of rel, or the self link for "self". rel may be
+// given as a CURIE or as the full URI it expands to, either form finds the
+// relation however it was added.
+func (l *Links) Get(rel string) []*Link {
+	if rel == SELF {
+		if l.Self == nil {
+			return nil
+		}
+		return []*Link{l.Self}
+	}
+	if key, ok := l.key(rel); ok {
+		return l.Relations[key]
+	}
 	return nil
 }
 
+// First returns the first link of rel, or nil when there is none
+func (l *Links) First(rel string) *Link {
+	links := l.Get(rel)
+	if len(links) == 0 {
+		return nil
+	}
+	return links[0]
+}
+
+// ByName returns the link of rel with the given name, or nil when there is none
+func (l *Links) ByName(rel string, name string) *Link {
+	for _, link := range l.Get(rel) {
+		if link != nil && link.Name == name {
+			return link
+		}
+	}
+	return nil
+}
+
+// Has reports whether rel holds any links
+func (l *Links) Has(rel string) bool {
+	return len(l.Get(rel)) > 0
+}
+
+// Remove removes every link of rel and reports whether there were any
+func (l *Links) Remove(rel string) bool {
+	if rel == SELF {
+		removed := l.Self != nil
+		l.Self = nil
+		return removed
+	}
+	key, ok := l.key(rel)
+	if !ok {
+		return false
+	}
+	delete(l.Relations, key)
+	l.SetCardinality(key, Many)
+	l.order = removeOrder(l.order, key)
+	return true
+}
+
+// Replace replaces the links of rel with links, keeping its position and
+// cardinality. The links are checked as AddLink checks them. Replacing with
+// no links removes the relation, "self" must be replaced with a single link.
+func (l *Links) Replace(rel string, links ...*Link) error {
+	if rel == SELF {
+		if len(links) != 1 {
+			return ErrCardinality
+		}
+		l.Self = links[0]
+		return nil
+	}
+	key, ok := l.key(rel)
+	if !ok {
+		key = rel
+	}
+	for _, link := range links {
+		err := l.checkLink(key, link)
+		if err != nil {
+			return err
+		}
+	}
+	if len(links) == 0 {
+		l.Remove(key)
+		return nil
+	}
+	if l.Relations == nil {
+		l.Relations = make(map[string][]*Link)
+	}
+	l.Relations[key] = append([]*Link(nil), links...)
+	l.order = appendOrder(l.order, key)
+	if len(links) > 1 {
+		l.SetCardinality(key, Many)
+	}
+	return nil
+}
+
+// Rels returns the relations holding links in sorted order. The self link
+// and curies are not included.
+func (l *Links) Rels() []string {
+	return orderedKeys(l.Relations, nil, defaultEncodeOptions)
+}
+
+// Range calls fn for the self link and then for every link of every
+// relation, in sorted order of relations. It stops when fn returns false.
+func (l *Links) Range(fn func(rel string, link *Link) bool) {
+	if l.Self != nil && !fn(SELF, l.Self) {
+		return
+	}
+	for _, rel := range l.Rels() {
+		for _, link := range l.Relations[rel] {
+			if !fn(rel, link) {
+				return
+			}
+		}
+	}
+}
+
+// key returns the relation of l matching rel in either CURIE or URI form
+func (l *Links) key(rel string) (string, bool) {
+	return matchRel(l.Relations, rel, l.ExpandRel)
+}
+
+// matchRel returns the key of m that is rel, or that expands to the same
+// relation as rel. Keys are tried in sorted order.
+func matchRel[V any](m map[string]V, rel string, expand func(string) string) (string, bool) {
+	if _, ok := m[rel]; ok {
+		return rel, true
+	}
+	expanded := expand(rel)
+	for _, key := range orderedKeys(m, nil, defaultEncodeOptions) {
+		if expand(key) == expanded {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// renameRel moves the links of rel to newRel, keeping its position and
+// cardinality. Links already held by newRel are kept first.
+func (l *Links) renameRel(rel string, newRel string) {
+	if rel == newRel {
+		return
+	}
+	links, ok := l.Relations[rel]
+	if !ok {
+		return
+	}
+	cardinality := l.Cardinality(rel)
+	existing, merged := l.Relations[newRel]
+	l.Relations[newRel] = append(existing, links...)
+	delete(l.Relations, rel)
+	l.SetCardinality(rel, Many)
+	if merged {
+		l.SetCardinality(newRel, Many)
+	} else {
+		l.SetCardinality(newRel, cardinality)
+	}
+	l.order = renameOrder(l.order, rel, newRel, merged)
+}
+
+// clone returns a copy of l that can be modified without affecting l
+func (l *Links) clone() *Links {
+	c := &Links{
+		Self:      l.Self,
+		Curies:    append([]Curie(nil), l.Curies...),
+		Relations: make(map[string][]*Link, len(l.Relations)),
+		order:     append([]string(nil), l.order...),
+		parent:    l.parent,
+		registry:  l.registry,
+	}
+	if len(c.Curies) == 0 {
+		c.Curies = nil
+	}
+	for rel, links := range l.Relations {
+		c.Relations[rel] = append([]*Link(nil), links...)
+	}
+	for rel, cardinality := range l.cardinality {
+		c.SetCardinality(rel, cardinality)
+	}
+	return c
+}
+
 // NewLinks creates and initializes Links
 func NewLinks() *Links {
 	return &Links{
@@ -21,8 +21,9 @@ func TestLinksMarshal(t *testing.T) {
 	hreflang := "en_US"
 	profile := "string uri"
 	err := r.AddLink("bar:baz", &Link{Href: "/bar/{item}", Templated: true, Title: title, Deprecation: deprecation, Type: typeval, HrefLang: hreflang, Profile: profile})
-	assert.NotNil(t, err, "expected an error from adding link")
-	assert.Equal(t, err, ErrNoCurie, "Expected ErrNoCurie to be returned")
+	assert.Nil(t, err, "curies are checked once the resource is embedded")
+	assert.ErrorIs(t, r.Validate(), ErrNoCurie, "Expected ErrNoCurie to be reported")
+	r.RemoveLink("bar:baz")
 	r.AddCurie(&Curie{Name: "bar", Templated: true, Href: "/docs/bar"})
 	err = r.AddLink("bar:baz", &Link{Href: "/bar/{item}", Templated: true, Title: title, Deprecation: deprecation, Type: typeval, HrefLang: hreflang, Profile: profile})
 	assert.Nil(t, err, "expected no error from adding link")
@@ -114,11 +115,12 @@ func TestLinksUnmarshal(t *testing.T) {
 
 func TestAddLinkBeforeCurie(t *testing.T) {
 	r := NewResource[any]()
-	err := r.AddLink("foo:bar", &Link{Href: "/foo"})
+	assert.Nil(t, r.AddLink("foo:bar", &Link{Href: "/foo"}))
+	err := r.Validate()
 	assert.NotNil(t, err)
 
 	r.AddCurie(&Curie{Href: "/docs/bar/{rel}", Name: "bar"})
-	err2 := r.AddLink("foo:bar", &Link{Href: "/foo"})
+	err2 := r.Validate()
 	assert.NotNil(t, err2)
 	assert.Equal(t, err, err2, "Same errors for link before curie")
 }
@@ -202,6 +204,13 @@ func TestLinksAddLinkWithColonPrefix(t *testing.T) {
 	assert.Len(t, links.Relations[":special"], 1)
 }
 
+func TestLinksAddNilLink(t *testing.T) {
+	links := NewLinks()
+	assert.Equal(t, ErrNilLink, links.AddLink("next", nil))
+	assert.Equal(t, ErrNilLink, links.Replace("next", &Link{Href: "/next"}, nil))
+	assert.False(t, links.Has("next"))
+}
+
 func TestSelfLinkWithAllProperties(t *testing.T) {
 	// Test that self links can have all Link properties, not just href
 	jsonData := `{
@@ -266,3 +275,137 @@ func TestLinkNameInRelation(t *testing.T) {
 	assert.Equal(t, "/api/items", links.Relations["items"][0].Href)
 	assert.Equal(t, "item-link", links.Relations["items"][0].Name)
 }
+
+func TestLinksSingleObjectRelation(t *testing.T) {
+	jsonData := `{"self":{"href":"/"},"author":{"href":"/people/1","title":"Author"},"items":[{"href":"/items/1"}]}`
+
+	var links Links
+	err := json.Unmarshal([]byte(jsonData), &links)
+	assert.Nil(t, err)
+	assert.Len(t, links.Relations["author"], 1)
+	assert.Equal(t, "/people/1", links.Relations["author"][0].Href)
+	assert.Equal(t, "Author", links.Relations["author"][0].Title)
+	assert.Equal(t, Single, links.Cardinality("author"))
+	assert.Equal(t, Many, links.Cardinality("items"))
+
+	b, err := json.Marshal(&links)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"self":{"href":"/"},"author":{"href":"/people/1","title":"Author"},"items":[{"href":"/items/1"}]}`, string(b))
+}
+
+func TestLinksAddLinkCardinality(t *testing.T) {
+	links := NewLinks()
+	assert.Nil(t, links.AddLink("author", &Link{Href: "/people/1"}, Single))
+	assert.Nil(t, links.AddLink("item", &Link{Href: "/items/1"}))
+	assert.Nil(t, links.AddLink("list", &Link{Href: "/lists/1"}, Many))
+
+	b, err := json.Marshal(links)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"author":{"href":"/people/1"},"item":[{"href":"/items/1"}],"list":[{"href":"/lists/1"}]}`, string(b))
+
+	// Forcing single on a relation that already has links is an error
+	assert.Equal(t, ErrCardinality, links.AddLink("item", &Link{Href: "/items/2"}, Single))
+
+	// Growing a single relation turns it into an array
+	assert.Nil(t, links.AddLink("author", &Link{Href: "/people/2"}))
+	assert.Equal(t, Many, links.Cardinality("author"))
+
+	// Forcing an array on a relation decoded as a single object
+	var decoded Links
+	assert.Nil(t, json.Unmarshal([]byte(`{"author":{"href":"/people/1"}}`), &decoded))
+	decoded.SetCardinality("author", Many)
+	b, err = json.Marshal(&decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"author":[{"href":"/people/1"}]}`, string(b))
+}
+
+func TestLinkExtensions(t *testing.T) {
+	jsonData := `{"self":{"href":"/","x-cache":"hit"},"edit":[{"href":"/orders/1","method":"PUT","expires":3600,"x-vendor":{"id":"abc"}}],"author":{"href":"/people/1","method":"GET"}}`
+
+	var links Links
+	err := json.Unmarshal([]byte(jsonData), &links)
+	assert.Nil(t, err)
+
+	value, ok := links.Self.Extension("x-cache")
+	assert.True(t, ok)
+	assert.Equal(t, "hit", value)
+
+	edit := links.Relations["edit"][0]
+	assert.Equal(t, "/orders/1", edit.Href)
+	assert.Equal(t, "PUT", edit.Extensions["method"])
+	assert.Equal(t, float64(3600), edit.Extensions["expires"])
+	assert.Equal(t, map[string]any{"id": "abc"}, edit.Extensions["x-vendor"])
+	_, ok = edit.Extension(HREF)
+	assert.False(t, ok, "HAL properties are not extensions")
+
+	b, err := json.Marshal(&links)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"self":{"href":"/","x-cache":"hit"},"author":{"href":"/people/1","method":"GET"},"edit":[{"href":"/orders/1","expires":3600,"method":"PUT","x-vendor":{"id":"abc"}}]}`, string(b))
+}
+
+func TestLinkSetExtension(t *testing.T) {
+	link := (&Link{}).SetHref("/orders/1").SetExtension("method", "DELETE").SetExtension(HREF, "/ignored")
+
+	value, ok := link.Extension("method")
+	assert.True(t, ok)
+	assert.Equal(t, "DELETE", value)
+
+	b, err := json.Marshal(link)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"href":"/orders/1","method":"DELETE"}`, string(b))
+
+	b, err = json.Marshal(&Link{Extensions: map[string]any{"method": "GET"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"method":"GET"}`, string(b))
+
+	_, err = json.Marshal(&Link{Href: "/", Extensions: map[string]any{"bad": make(chan int)}})
+	assert.NotNil(t, err)
+}
+
+func TestLinksNavigation(t *testing.T) {
+	links := NewLinks()
+	links.Self = &Link{Href: "/orders"}
+	links.AddCurie(&Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true})
+	links.AddLink("ea:admin", &Link{Href: "/admins/2", Name: "v1"})
+	links.AddLink("ea:admin", &Link{Href: "/admins/5", Name: "v2"})
+	links.AddLink("next", &Link{Href: "/orders?page=2"}, Single)
+
+	assert.Equal(t, []string{"ea:admin", "next"}, links.Rels())
+	assert.Equal(t, "/orders", links.First("self").Href)
+	assert.Equal(t, "/admins/2", links.First("ea:admin").Href)
+	assert.Equal(t, "/admins/5", links.ByName("http://example.com/docs/rels/admin", "v2").Href)
+	assert.Nil(t, links.ByName("ea:admin", "v3"))
+	assert.Nil(t, links.First("prev"))
+	assert.True(t, links.Has("next"))
+	assert.False(t, links.Has("prev"))
+
+	var visited []string
+	links.Range(func(rel string, link *Link) bool {
+		visited = append(visited, rel+" "+link.Href)
+		return true
+	})
+	assert.Equal(t, []string{"self /orders", "ea:admin /admins/2", "ea:admin /admins/5", "next /orders?page=2"}, visited)
+
+	visited = nil
+	links.Range(func(rel string, link *Link) bool {
+		visited = append(visited, rel)
+		return rel != "ea:admin"
+	})
+	assert.Equal(t, []string{"self", "ea:admin"}, visited, "Range stops when fn returns false")
+
+	assert.Nil(t, links.Replace("next", &Link{Href: "/orders?page=3"}))
+	assert.Equal(t, Single, links.Cardinality("next"))
+	assert.Equal(t, "/orders?page=3", links.First("next").Href)
+	assert.Equal(t, ErrCardinality, links.Replace("self"))
+	assert.Nil(t, links.Replace("self", &Link{Href: "/orders/"}))
+	assert.Equal(t, "/orders/", links.Self.Href)
+
+	assert.True(t, links.Remove("http://example.com/docs/rels/admin"))
+	assert.False(t, links.Remove("ea:admin"))
+	assert.True(t, links.Remove("self"))
+	assert.Nil(t, links.Get("self"))
+
+	b, err := json.Marshal(links)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"curies":[{"name":"ea","href":"http://example.com/docs/rels/{rel}","templated":true}],"next":{"href":"/orders?page=3"}}`, string(b))
+}
@@ -0,0 +1,253 @@
+package haljson
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Relations written by Paginate
+const (
+	// PAGE is the relation of the templated link to any page
+	PAGE = "page"
+)
+
+// paginationRels are the relations Paginate manages, in the order they are written
+var paginationRels = []string{string(RelFirst), string(RelPrev), string(RelNext), string(RelLast), PAGE}
+
+// Paging is a strategy for addressing the pages of a collection, one of
+// PageNumber, OffsetLimit or Cursor
+type Paging interface {
+	// pages returns the query parameters of each page to link to by
+	// relation. Relations that do not apply are left out.
+	pages() (map[string]url.Values, error)
+	// template returns the variable of a templated page link, and the
+	// query parameters it is added to, or an empty name for none
+	template() (string, url.Values)
+}
+
+// PageNumber pages a collection by page number, starting at 1, and page size
+type PageNumber struct {
+	Page int
+	Size int
+	// Total is the number of items in the collection, negative when it is
+	// not known. When it is not known there is no last link and next is
+	// always written.
+	Total int
+	// PageParam and SizeParam name the query parameters, page and size by default
+	PageParam string
+	SizeParam string
+	// Templated adds a templated page link for clients to pick any page
+	Templated bool
+}
+
+// pages implements Paging
+func (p PageNumber) pages() (map[string]url.Values, error) {
+	if p.Page < 1 || p.Size < 1 {
+		return nil, fmt.Errorf("%w: page %d of size %d", ErrInvalidPaging, p.Page, p.Size)
+	}
+	page := func(n int) url.Values {
+		return url.Values{
+			withDefault(p.PageParam, "page"): {fmt.Sprint(n)},
+			withDefault(p.SizeParam, "size"): {fmt.Sprint(p.Size)},
+		}
+	}
+	pages := map[string]url.Values{string(RelFirst): page(1)}
+	if p.Page > 1 {
+		pages[string(RelPrev)] = page(p.Page - 1)
+	}
+	if p.Total < 0 {
+		pages[string(RelNext)] = page(p.Page + 1)
+		return pages, nil
+	}
+	last := max(1, (p.Total+p.Size-1)/p.Size)
+	if p.Page < last {
+		pages[string(RelNext)] = page(p.Page + 1)
+	}
+	pages[string(RelLast)] = page(last)
+	return pages, nil
+}
+
+// template implements Paging
+func (p PageNumber) template() (string, url.Values) {
+	if !p.Templated {
+		return "", nil
+	}
+	return withDefault(p.PageParam, "page"), url.Values{withDefault(p.SizeParam, "size"): {fmt.Sprint(p.Size)}}
+}
+
+// OffsetLimit pages a collection by the offset of its first item, starting
+// at 0, and the number of items per page
+type OffsetLimit struct {
+	Offset int
+	Limit  int
+	// Total is the number of items in the collection, negative when it is
+	// not known. When it is not known there is no last link and next is
+	// always written.
+	Total int
+	// OffsetParam and LimitParam name the query parameters, offset and limit by default
+	OffsetParam string
+	LimitParam  string
+	// Templated adds a templated page link for clients to pick any offset
+	Templated bool
+}
+
+// pages implements Paging
+func (p OffsetLimit) pages() (map[string]url.Values, error) {
+	if p.Offset < 0 || p.Limit < 1 {
+		return nil, fmt.Errorf("%w: offset %d with limit %d", ErrInvalidPaging, p.Offset, p.Limit)
+	}
+	page := func(offset int) url.Values {
+		return url.Values{
+			withDefault(p.OffsetParam, "offset"): {fmt.Sprint(offset)},
+			withDefault(p.LimitParam, "limit"):   {fmt.Sprint(p.Limit)},
+		}
+	}
+	pages := map[string]url.Values{string(RelFirst): page(0)}
+	if p.Offset > 0 {
+		pages[string(RelPrev)] = page(max(0, p.Offset-p.Limit))
+	}
+	if p.Total < 0 {
+		pages[string(RelNext)] = page(p.Offset + p.Limit)
+		return pages, nil
+	}
+	if p.Offset+p.Limit < p.Total {
+		pages[string(RelNext)] = page(p.Offset + p.Limit)
+	}
+	pages[string(RelLast)] = page(max(0, (p.Total-1)/p.Limit*p.Limit))
+	return pages, nil
+}
+
+// template implements Paging
+func (p OffsetLimit) template() (string, url.Values) {
+	if !p.Templated {
+		return "", nil
+	}
+	return withDefault(p.OffsetParam, "offset"), url.Values{withDefault(p.LimitParam, "limit"): {fmt.Sprint(p.Limit)}}
+}
+
+// Cursor pages a collection with opaque cursors. Links are only written for
+// the cursors that are set, first links to the collection without a cursor.
+type Cursor struct {
+	Prev string
+	Next string
+	Last string
+	// Size is the number of items per page, it is left out when zero
+	Size int
+	// CursorParam and SizeParam name the query parameters, cursor and size by default
+	CursorParam string
+	SizeParam   string
+}
+
+// pages implements Paging
+func (p Cursor) pages() (map[string]url.Values, error) {
+	if p.Size < 0 {
+		return nil, fmt.Errorf("%w: size %d", ErrInvalidPaging, p.Size)
+	}
+	page := func(cursor string) url.Values {
+		// The first page has no cursor, any cursor of the base is removed
+		values := url.Values{withDefault(p.CursorParam, "cursor"): nil}
+		if cursor != "" {
+			values.Set(withDefault(p.CursorParam, "cursor"), cursor)
+		}
+		if p.Size > 0 {
+			values.Set(withDefault(p.SizeParam, "size"), fmt.Sprint(p.Size))
+		}
+		return values
+	}
+	pages := map[string]url.Values{string(RelFirst): page("")}
+	for rel, cursor := range map[string]string{string(RelPrev): p.Prev, string(RelNext): p.Next, string(RelLast): p.Last} {
+		if cursor != "" {
+			pages[rel] = page(cursor)
+		}
+	}
+	return pages, nil
+}
+
+// template implements Paging, cursors cannot be templated
+func (p Cursor) template() (string, url.Values) {
+	return "", nil
+}
+
+// Paginate writes the first, prev, next and last links of the page
+// described by paging, relative to base, as single Link Objects. Links that
+// do not apply are removed. Query parameters of base are kept, those of the
+// strategy replace them. The relations Paginate writes are accepted whatever
+// the registry of l.
+func (l *Links) Paginate(base *url.URL, paging Paging) error {
+	pages, err := paging.pages()
+	if err != nil {
+		return err
+	}
+	links := make(map[string]*Link, len(pages)+1)
+	for rel, values := range pages {
+		links[rel] = &Link{Href: pageURL(base, values)}
+	}
+	if name, values := paging.template(); name != "" {
+		links[PAGE] = &Link{Href: pageTemplate(base, values, name), Templated: true}
+	}
+
+	// Check every link before changing any, the relations are not checked
+	// against the registry as page is not registered with IANA
+	for _, link := range links {
+		if link.Templated {
+			err = ValidateURITemplate(link.Href)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, rel := range paginationRels {
+		l.Remove(rel)
+		if link, ok := links[rel]; ok {
+			err = l.addLink(rel, link, Single)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pageURL returns base with the query parameters of values set, parameters
+// without values are removed
+func pageURL(base *url.URL, values url.Values) string {
+	u := *base
+	query := u.Query()
+	for key, value := range values {
+		if len(value) == 0 {
+			delete(query, key)
+			continue
+		}
+		query[key] = value
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// pageTemplate returns base with the query parameters of values set, and a
+// form-style expression adding the variable name to its query
+func pageTemplate(base *url.URL, values url.Values, name string) string {
+	u := *base
+	u.Fragment = ""
+	u.RawFragment = ""
+	query := u.Query()
+	for key, value := range values {
+		query[key] = value
+	}
+	// The template sets the parameter itself
+	query.Del(name)
+	u.RawQuery = query.Encode()
+	operator := "?"
+	if u.RawQuery != "" {
+		operator = "&"
+	}
+	return u.String() + "{" + operator + name + "}"
+}
+
+// withDefault returns value, or fallback when it is empty
+func withDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,111 @@
+package haljson
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginatePageNumber(t *testing.T) {
+	base, _ := url.Parse("/orders?status=shipped&page=9")
+
+	r := NewResource[any]()
+	r.Self("/orders?status=shipped&page=2")
+	err := r.Paginate(base, PageNumber{Page: 2, Size: 20, Total: 45, Templated: true})
+	assert.Nil(t, err)
+
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"_links": {
+		"self": {"href": "/orders?status=shipped&page=2"},
+		"first": {"href": "/orders?page=1&size=20&status=shipped"},
+		"prev": {"href": "/orders?page=1&size=20&status=shipped"},
+		"next": {"href": "/orders?page=3&size=20&status=shipped"},
+		"last": {"href": "/orders?page=3&size=20&status=shipped"},
+		"page": {"href": "/orders?size=20&status=shipped{&page}", "templated": true}
+	}}`, string(b))
+
+	href, err := r.FirstLink("page").Expand(map[string]any{"page": 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "/orders?size=20&status=shipped&page=2", href)
+
+	// Paginating again replaces the links, dropping those that no longer apply
+	err = r.Paginate(base, PageNumber{Page: 3, Size: 20, Total: 45})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"first", "last", "prev"}, r.LinkRels())
+	assert.Equal(t, "/orders?page=3&size=20&status=shipped", r.FirstLink("last").Href)
+}
+
+func TestPaginatePageNumberEdges(t *testing.T) {
+	base, _ := url.Parse("http://example.com/orders")
+
+	links := NewLinks()
+	assert.Nil(t, links.Paginate(base, PageNumber{Page: 1, Size: 10, Total: 0}))
+	assert.Equal(t, []string{"first", "last"}, links.Rels())
+	assert.Equal(t, "http://example.com/orders?page=1&size=10", links.First("last").Href)
+
+	assert.Nil(t, links.Paginate(base, PageNumber{Page: 4, Size: 10, Total: -1, PageParam: "p", SizeParam: "per_page"}))
+	assert.Equal(t, []string{"first", "next", "prev"}, links.Rels(), "last is unknown without a total")
+	assert.Equal(t, "http://example.com/orders?p=5&per_page=10", links.First("next").Href)
+
+	err := links.Paginate(base, PageNumber{Page: 0, Size: 10})
+	assert.True(t, errors.Is(err, ErrInvalidPaging))
+	assert.Equal(t, []string{"first", "next", "prev"}, links.Rels(), "invalid paging leaves links unchanged")
+}
+
+func TestPaginateOffsetLimit(t *testing.T) {
+	base, _ := url.Parse("/orders?q=x")
+
+	links := NewLinks()
+	assert.Nil(t, links.Paginate(base, OffsetLimit{Offset: 25, Limit: 10, Total: 41, Templated: true}))
+	assert.Equal(t, "/orders?limit=10&offset=0&q=x", links.First("first").Href)
+	assert.Equal(t, "/orders?limit=10&offset=15&q=x", links.First("prev").Href)
+	assert.Equal(t, "/orders?limit=10&offset=35&q=x", links.First("next").Href)
+	assert.Equal(t, "/orders?limit=10&offset=40&q=x", links.First("last").Href)
+	assert.Equal(t, "/orders?limit=10&q=x{&offset}", links.First("page").Href)
+
+	assert.Nil(t, links.Paginate(base, OffsetLimit{Offset: 35, Limit: 10, Total: 41}))
+	assert.Nil(t, links.First("next"))
+	assert.Nil(t, links.First("page"))
+
+	err := links.Paginate(base, OffsetLimit{Offset: -1, Limit: 10})
+	assert.True(t, errors.Is(err, ErrInvalidPaging))
+}
+
+func TestPaginateCursor(t *testing.T) {
+	base, _ := url.Parse("https://example.com/events?type=order")
+
+	r := NewStateResource(struct{}{})
+	assert.Nil(t, r.Paginate(base, Cursor{Next: "b2Zmc2V0PTIw", Size: 20}))
+	assert.Equal(t, []string{"first", "next"}, r.LinkRels())
+	assert.Equal(t, "https://example.com/events?size=20&type=order", r.FirstLink("first").Href)
+	assert.Equal(t, "https://example.com/events?cursor=b2Zmc2V0PTIw&size=20&type=order", r.FirstLink("next").Href)
+
+	assert.Nil(t, r.Paginate(base, Cursor{Prev: "a", Last: "z", CursorParam: "after"}))
+	assert.Equal(t, []string{"first", "last", "prev"}, r.LinkRels())
+	assert.Equal(t, "https://example.com/events?after=z&type=order", r.FirstLink("last").Href)
+
+	// The first page drops the cursor of the current page
+	current, _ := url.Parse("/events?cursor=abc&size=20")
+	assert.Nil(t, r.Paginate(current, Cursor{Next: "def", Size: 20}))
+	assert.Equal(t, "/events?size=20", r.FirstLink("first").Href)
+	assert.Equal(t, "/events?cursor=def&size=20", r.FirstLink("next").Href)
+}
+
+func TestPaginateRegistry(t *testing.T) {
+	base, _ := url.Parse("/orders")
+
+	r := NewValidatedResource[any](nil)
+	assert.Nil(t, r.Paginate(base, PageNumber{Page: 1, Size: 10, Total: 5}))
+	// page is not an IANA relation, Paginate writes it all the same
+	assert.Nil(t, r.Paginate(base, PageNumber{Page: 1, Size: 10, Total: 5, Templated: true}))
+	assert.Equal(t, []string{"first", "last", "page"}, r.LinkRels())
+	assert.Equal(t, "/orders?size=10{&page}", r.FirstLink("page").Href)
+
+	// Links added by other means are still checked
+	err := r.AddLink(PAGE, &Link{Href: "/orders?page=2"})
+	assert.True(t, errors.Is(err, ErrUnregisteredRel))
+}
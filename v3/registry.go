@@ -0,0 +1,115 @@
+package haljson
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Registry is a set of link relation types. When a Registry is set on Links,
+// AddLink only accepts relation types that are registered, absolute URIs or
+// CURIEs. An API registers its own extension relation types in a Registry
+// extending IANA().
+type Registry struct {
+	base      *Registry
+	relations map[string]string
+}
+
+// iana is the Registry of the link relation types registered with IANA.
+// It is never handed out, so that it cannot be modified.
+var iana = newIANARegistry()
+
+// IANA returns a Registry of the link relation types registered with IANA.
+// Each call returns a new Registry, relation types registered on it do not
+// affect other callers.
+func IANA() *Registry {
+	return NewRegistry(iana)
+}
+
+// NewRegistry creates an empty Registry extending base, which may be nil
+func NewRegistry(base *Registry) *Registry {
+	return &Registry{
+		base:      base,
+		relations: make(map[string]string),
+	}
+}
+
+// newIANARegistry creates the Registry of IANA link relation types
+func newIANARegistry() *Registry {
+	registry := NewRegistry(nil)
+	for rel, description := range ianaRelations {
+		registry.Register(string(rel), description)
+	}
+	return registry
+}
+
+// Register adds rel, with a description, to the registry, chainable
+func (reg *Registry) Register(rel string, description string) *Registry {
+	reg.relations[strings.ToLower(rel)] = description
+	return reg
+}
+
+// Lookup returns the description of rel and whether it is registered, in
+// the registry or the registries it extends. Relation types are compared
+// case-insensitively, as RFC 8288 requires.
+func (reg *Registry) Lookup(rel string) (string, bool) {
+	rel = strings.ToLower(rel)
+	for r := reg; r != nil; r = r.base {
+		if description, ok := r.relations[rel]; ok {
+			return description, true
+		}
+	}
+	return "", false
+}
+
+// Rels returns every registered relation type, including those of the
+// registries it extends, in sorted order
+func (reg *Registry) Rels() []string {
+	seen := make(map[string]bool)
+	for r := reg; r != nil; r = r.base {
+		for rel := range r.relations {
+			seen[rel] = true
+		}
+	}
+	rels := make([]string, 0, len(seen))
+	for rel := range seen {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	return rels
+}
+
+// Validate reports whether rel is a registered relation type, an absolute
+// URI or a CURIE. It does not check that the curie of a CURIE is declared,
+// AddLink does.
+func (reg *Registry) Validate(rel string) error {
+	if isAbsoluteRel(rel) {
+		return nil
+	}
+	if prefix, reference, ok := strings.Cut(rel, ":"); ok {
+		if prefix == "" || reference == "" {
+			return fmt.Errorf("%w: %q", ErrInvalidRel, rel)
+		}
+		return nil
+	}
+	if _, ok := reg.Lookup(rel); !ok {
+		return fmt.Errorf("%w: %q", ErrUnregisteredRel, rel)
+	}
+	return nil
+}
+
+// isAbsoluteRel reports whether rel is an absolute URI with an authority,
+// such as http://example.com/rels/orders, rather than a CURIE
+func isAbsoluteRel(rel string) bool {
+	scheme, rest, ok := strings.Cut(rel, ":")
+	if !ok || scheme == "" || !strings.HasPrefix(rest, "//") {
+		return false
+	}
+	for i, c := range scheme {
+		letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (i == 0 || !(c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.')) {
+			return false
+		}
+	}
+	return true
+}
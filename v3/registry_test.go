@@ -0,0 +1,101 @@
+package haljson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIANARegistry(t *testing.T) {
+	description, ok := IANA().Lookup("next")
+	assert.True(t, ok)
+	assert.Equal(t, RelNext.Description(), description)
+	assert.Equal(t, "next", RelNext.String())
+
+	_, ok = IANA().Lookup("NEXT")
+	assert.True(t, ok, "relation types are case-insensitive")
+	_, ok = IANA().Lookup("nxet")
+	assert.False(t, ok)
+	assert.Empty(t, LinkRelation("nxet").Description())
+
+	assert.Len(t, IANA().Rels(), len(ianaRelations))
+	for rel, description := range ianaRelations {
+		assert.NotEmpty(t, description, rel)
+	}
+}
+
+func TestRegistryExtends(t *testing.T) {
+	registry := NewRegistry(IANA()).Register("warehouse", "Refers to the warehouse holding an order.")
+
+	description, ok := registry.Lookup("Warehouse")
+	assert.True(t, ok)
+	assert.Equal(t, "Refers to the warehouse holding an order.", description)
+	_, ok = registry.Lookup("self")
+	assert.True(t, ok)
+	_, ok = IANA().Lookup("warehouse")
+	assert.False(t, ok, "extending does not modify the base registry")
+	assert.Len(t, registry.Rels(), len(IANA().Rels())+1)
+}
+
+func TestRegistryValidate(t *testing.T) {
+	tests := []struct {
+		rel string
+		err error
+	}{
+		{"next", nil},
+		{"http://example.com/rels/orders", nil},
+		{"urn+x-y.1://example/orders", nil},
+		{"ea:orders", nil},
+		{"nxet", ErrUnregisteredRel},
+		{":special", ErrInvalidRel},
+		{"ea:", ErrInvalidRel},
+	}
+	for _, tt := range tests {
+		err := IANA().Validate(tt.rel)
+		if tt.err == nil {
+			assert.Nil(t, err, tt.rel)
+		} else {
+			assert.True(t, errors.Is(err, tt.err), tt.rel)
+		}
+	}
+}
+
+func TestLinksRegistry(t *testing.T) {
+	r := NewResource[any]()
+	r.SetRegistry(NewRegistry(IANA()).Register("warehouse", ""))
+	r.AddCurie(&Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true})
+
+	assert.Nil(t, r.AddLink(string(RelNext), &Link{Href: "/orders?page=2"}))
+	assert.Nil(t, r.AddLink("warehouse", &Link{Href: "/warehouses/1"}))
+	assert.Nil(t, r.AddLink("ea:orders", &Link{Href: "/orders"}))
+	assert.Nil(t, r.AddLink("http://example.com/rels/invoices", &Link{Href: "/invoices"}))
+	assert.True(t, errors.Is(r.AddLink("nxet", &Link{Href: "/orders?page=2"}), ErrUnregisteredRel))
+	assert.True(t, errors.Is(r.ReplaceLinks("nxet", &Link{Href: "/"}), ErrUnregisteredRel))
+
+	// Embedded resources use the registry of their parent
+	order := NewResource[any]()
+	r.AddEmbed("ea:order", order)
+	assert.True(t, errors.Is(order.AddLink("customr", &Link{Href: "/customers/1"}), ErrUnregisteredRel))
+	assert.Nil(t, order.AddLink("author", &Link{Href: "/customers/1"}))
+
+	// Without a registry any relation type is accepted
+	assert.Nil(t, NewLinks().AddLink("nxet", &Link{Href: "/"}))
+}
+
+func TestNewValidatedResource(t *testing.T) {
+	r := NewValidatedResource[any](nil)
+	assert.Nil(t, r.AddLink(string(RelNext), &Link{Href: "/orders?page=2"}))
+	assert.True(t, errors.Is(r.AddLink("nxet", &Link{Href: "/orders?page=2"}), ErrUnregisteredRel))
+
+	state := NewValidatedStateResource(map[string]int{"total": 1}, IANA().Register("warehouse", ""))
+	assert.Nil(t, state.AddLink("warehouse", &Link{Href: "/warehouses/1"}))
+	assert.True(t, errors.Is(state.AddLink("customr", &Link{Href: "/customers/1"}), ErrUnregisteredRel))
+}
+
+func TestIANARegistryReadOnly(t *testing.T) {
+	IANA().Register("warehouse", "")
+	_, ok := IANA().Lookup("warehouse")
+	assert.False(t, ok, "registering on IANA() does not modify the IANA registry")
+	assert.True(t, errors.Is(NewValidatedResource[any](nil).AddLink("warehouse", &Link{Href: "/"}), ErrUnregisteredRel))
+}
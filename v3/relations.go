@@ -0,0 +1,281 @@
+package haljson
+
+// LinkRelation is a link relation type registered with IANA, see
+// https://www.iana.org/assignments/link-relations/link-relations.xhtml
+type LinkRelation string
+
+// Link relation types registered with IANA
+const (
+	RelAbout                  LinkRelation = "about"
+	RelACL                    LinkRelation = "acl"
+	RelAlternate              LinkRelation = "alternate"
+	RelAMPHTML                LinkRelation = "amphtml"
+	RelAPICatalog             LinkRelation = "api-catalog"
+	RelAppendix               LinkRelation = "appendix"
+	RelAppleTouchIcon         LinkRelation = "apple-touch-icon"
+	RelAppleTouchStartupImage LinkRelation = "apple-touch-startup-image"
+	RelArchives               LinkRelation = "archives"
+	RelAuthor                 LinkRelation = "author"
+	RelBlockedBy              LinkRelation = "blocked-by"
+	RelBookmark               LinkRelation = "bookmark"
+	RelC2PAManifest           LinkRelation = "c2pa-manifest"
+	RelCanonical              LinkRelation = "canonical"
+	RelChapter                LinkRelation = "chapter"
+	RelCiteAs                 LinkRelation = "cite-as"
+	RelCollection             LinkRelation = "collection"
+	RelCompressionDictionary  LinkRelation = "compression-dictionary"
+	RelContents               LinkRelation = "contents"
+	RelConvertedFrom          LinkRelation = "convertedfrom"
+	RelCopyright              LinkRelation = "copyright"
+	RelCreateForm             LinkRelation = "create-form"
+	RelCurrent                LinkRelation = "current"
+	RelDeprecation            LinkRelation = "deprecation"
+	RelDescribedBy            LinkRelation = "describedby"
+	RelDescribes              LinkRelation = "describes"
+	RelDisclosure             LinkRelation = "disclosure"
+	RelDNSPrefetch            LinkRelation = "dns-prefetch"
+	RelDuplicate              LinkRelation = "duplicate"
+	RelEdit                   LinkRelation = "edit"
+	RelEditForm               LinkRelation = "edit-form"
+	RelEditMedia              LinkRelation = "edit-media"
+	RelEnclosure              LinkRelation = "enclosure"
+	RelExternal               LinkRelation = "external"
+	RelFirst                  LinkRelation = "first"
+	RelGeofeed                LinkRelation = "geofeed"
+	RelGlossary               LinkRelation = "glossary"
+	RelHelp                   LinkRelation = "help"
+	RelHosts                  LinkRelation = "hosts"
+	RelHub                    LinkRelation = "hub"
+	RelICEServer              LinkRelation = "ice-server"
+	RelIcon                   LinkRelation = "icon"
+	RelIndex                  LinkRelation = "index"
+	RelIntervalAfter          LinkRelation = "intervalafter"
+	RelIntervalBefore         LinkRelation = "intervalbefore"
+	RelIntervalContains       LinkRelation = "intervalcontains"
+	RelIntervalDisjoint       LinkRelation = "intervaldisjoint"
+	RelIntervalDuring         LinkRelation = "intervalduring"
+	RelIntervalEquals         LinkRelation = "intervalequals"
+	RelIntervalFinishedBy     LinkRelation = "intervalfinishedby"
+	RelIntervalFinishes       LinkRelation = "intervalfinishes"
+	RelIntervalIn             LinkRelation = "intervalin"
+	RelIntervalMeets          LinkRelation = "intervalmeets"
+	RelIntervalMetBy          LinkRelation = "intervalmetby"
+	RelIntervalOverlappedBy   LinkRelation = "intervaloverlappedby"
+	RelIntervalOverlaps       LinkRelation = "intervaloverlaps"
+	RelIntervalStartedBy      LinkRelation = "intervalstartedby"
+	RelIntervalStarts         LinkRelation = "intervalstarts"
+	RelItem                   LinkRelation = "item"
+	RelLast                   LinkRelation = "last"
+	RelLatestVersion          LinkRelation = "latest-version"
+	RelLicense                LinkRelation = "license"
+	RelLinkset                LinkRelation = "linkset"
+	RelLRDD                   LinkRelation = "lrdd"
+	RelManifest               LinkRelation = "manifest"
+	RelMaskIcon               LinkRelation = "mask-icon"
+	RelMe                     LinkRelation = "me"
+	RelMediaFeed              LinkRelation = "media-feed"
+	RelMemento                LinkRelation = "memento"
+	RelMicropub               LinkRelation = "micropub"
+	RelModulePreload          LinkRelation = "modulepreload"
+	RelMonitor                LinkRelation = "monitor"
+	RelMonitorGroup           LinkRelation = "monitor-group"
+	RelNext                   LinkRelation = "next"
+	RelNextArchive            LinkRelation = "next-archive"
+	RelNoFollow               LinkRelation = "nofollow"
+	RelNoOpener               LinkRelation = "noopener"
+	RelNoReferrer             LinkRelation = "noreferrer"
+	RelOpener                 LinkRelation = "opener"
+	RelOpenID2LocalID         LinkRelation = "openid2.local_id"
+	RelOpenID2Provider        LinkRelation = "openid2.provider"
+	RelOriginal               LinkRelation = "original"
+	RelP3Pv1                  LinkRelation = "p3pv1"
+	RelPayment                LinkRelation = "payment"
+	RelPingback               LinkRelation = "pingback"
+	RelPreconnect             LinkRelation = "preconnect"
+	RelPredecessorVersion     LinkRelation = "predecessor-version"
+	RelPrefetch               LinkRelation = "prefetch"
+	RelPreload                LinkRelation = "preload"
+	RelPrerender              LinkRelation = "prerender"
+	RelPrev                   LinkRelation = "prev"
+	RelPrevArchive            LinkRelation = "prev-archive"
+	RelPreview                LinkRelation = "preview"
+	RelPrevious               LinkRelation = "previous"
+	RelPrivacyPolicy          LinkRelation = "privacy-policy"
+	RelProfile                LinkRelation = "profile"
+	RelPublication            LinkRelation = "publication"
+	RelRelated                LinkRelation = "related"
+	RelReplies                LinkRelation = "replies"
+	RelRestconf               LinkRelation = "restconf"
+	RelRuleInput              LinkRelation = "ruleinput"
+	RelSearch                 LinkRelation = "search"
+	RelSection                LinkRelation = "section"
+	RelSelf                   LinkRelation = "self"
+	RelService                LinkRelation = "service"
+	RelServiceDesc            LinkRelation = "service-desc"
+	RelServiceDoc             LinkRelation = "service-doc"
+	RelServiceMeta            LinkRelation = "service-meta"
+	RelSIPTrunkingCapability  LinkRelation = "sip-trunking-capability"
+	RelSponsored              LinkRelation = "sponsored"
+	RelStart                  LinkRelation = "start"
+	RelStatus                 LinkRelation = "status"
+	RelStylesheet             LinkRelation = "stylesheet"
+	RelSubsection             LinkRelation = "subsection"
+	RelSuccessorVersion       LinkRelation = "successor-version"
+	RelSunset                 LinkRelation = "sunset"
+	RelTag                    LinkRelation = "tag"
+	RelTermsOfService         LinkRelation = "terms-of-service"
+	RelTimeGate               LinkRelation = "timegate"
+	RelTimeMap                LinkRelation = "timemap"
+	RelType                   LinkRelation = "type"
+	RelUGC                    LinkRelation = "ugc"
+	RelUp                     LinkRelation = "up"
+	RelVersionHistory         LinkRelation = "version-history"
+	RelVia                    LinkRelation = "via"
+	RelWebmention             LinkRelation = "webmention"
+	RelWorkingCopy            LinkRelation = "working-copy"
+	RelWorkingCopyOf          LinkRelation = "working-copy-of"
+)
+
+// String returns the name of the link relation type
+func (rel LinkRelation) String() string {
+	return string(rel)
+}
+
+// Description returns the IANA description of the link relation type, or
+// an empty string when it is not registered
+func (rel LinkRelation) Description() string {
+	description, _ := iana.Lookup(string(rel))
+	return description
+}
+
+// ianaRelations describes the link relation types registered with IANA
+var ianaRelations = map[LinkRelation]string{
+	RelAbout:                  "Refers to a resource that is the subject of the link's context.",
+	RelACL:                    "Refers to a resource that controls access to the link's context.",
+	RelAlternate:              "Refers to a substitute for this context.",
+	RelAMPHTML:                "Used to reference alternative content that uses the AMP profile of the HTML format.",
+	RelAPICatalog:             "Refers to a list of APIs available from the publisher of the link context.",
+	RelAppendix:               "Refers to an appendix.",
+	RelAppleTouchIcon:         "Refers to an icon for the context. Synonym for icon.",
+	RelAppleTouchStartupImage: "Refers to a launch screen for the context.",
+	RelArchives:               "Refers to a collection of records, documents, or other materials of historical interest.",
+	RelAuthor:                 "Refers to the context's author.",
+	RelBlockedBy:              "Identifies the entity that blocks access to a resource following receipt of a legal demand.",
+	RelBookmark:               "Gives a permanent link to use for bookmarking purposes.",
+	RelC2PAManifest:           "Links to a C2PA Manifest associated with the link context.",
+	RelCanonical:              "Designates the preferred version of a resource.",
+	RelChapter:                "Refers to a chapter in a collection of resources.",
+	RelCiteAs:                 "Indicates that the link target is preferred over the link context for the purpose of permanent citation.",
+	RelCollection:             "The target IRI points to a resource which represents the collection resource for the context IRI.",
+	RelCompressionDictionary:  "Refers to a resource that can be used as a compression dictionary.",
+	RelContents:               "Refers to a table of contents.",
+	RelConvertedFrom:          "The document linked to was later converted to the document that contains this link relation.",
+	RelCopyright:              "Refers to a copyright statement that applies to the link's context.",
+	RelCreateForm:             "The target IRI points to a resource where a submission form can be obtained.",
+	RelCurrent:                "Refers to a resource containing the most recent item(s) in a collection of resources.",
+	RelDeprecation:            "Refers to a resource providing information about the link's context's deprecation.",
+	RelDescribedBy:            "Refers to a resource providing information about the link's context.",
+	RelDescribes:              "The relationship A describes B asserts that resource A provides a description of resource B.",
+	RelDisclosure:             "Refers to a list of patent disclosures made with respect to material for which the disclosure relation is specified.",
+	RelDNSPrefetch:            "Used to indicate an origin that will be used to fetch required resources for the link context.",
+	RelDuplicate:              "Refers to a resource whose available representations are byte-for-byte identical with the corresponding representations of the context IRI.",
+	RelEdit:                   "Refers to a resource that can be used to edit the link's context.",
+	RelEditForm:               "The target IRI points to a resource where a submission form for editing associated resource can be obtained.",
+	RelEditMedia:              "Refers to a resource that can be used to edit media associated with the link's context.",
+	RelEnclosure:              "Identifies a related resource that is potentially large and might require special handling.",
+	RelExternal:               "Refers to a resource that is not part of the same site as the current context.",
+	RelFirst:                  "An IRI that refers to the furthest preceding resource in a series of resources.",
+	RelGeofeed:                "Refers to a geofeed for the link context.",
+	RelGlossary:               "Refers to a glossary of terms.",
+	RelHelp:                   "Refers to context-sensitive help.",
+	RelHosts:                  "Refers to a resource hosted by the server indicated by the link context.",
+	RelHub:                    "Refers to a hub that enables registration for notification of updates to the context.",
+	RelICEServer:              "Conveys the STUN and TURN servers that can be used by an ICE Agent to establish a connection with a peer.",
+	RelIcon:                   "Refers to an icon representing the link's context.",
+	RelIndex:                  "Refers to an index.",
+	RelIntervalAfter:          "Refers to a resource associated with a time interval that ends before the beginning of the time interval associated with the context resource.",
+	RelIntervalBefore:         "Refers to a resource associated with a time interval that begins after the end of the time interval associated with the context resource.",
+	RelIntervalContains:       "Refers to a resource associated with a time interval that begins after the beginning of the time interval associated with the context resource, and ends before its end.",
+	RelIntervalDisjoint:       "Refers to a resource associated with a time interval that begins after the end of the time interval associated with the context resource, or ends before its beginning.",
+	RelIntervalDuring:         "Refers to a resource associated with a time interval that begins before the beginning of the time interval associated with the context resource, and ends after its end.",
+	RelIntervalEquals:         "Refers to a resource associated with a time interval whose beginning coincides with the beginning of the time interval associated with the context resource, and whose end coincides with its end.",
+	RelIntervalFinishedBy:     "Refers to a resource associated with a time interval that begins after the beginning of the time interval associated with the context resource, and whose end coincides with its end.",
+	RelIntervalFinishes:       "Refers to a resource associated with a time interval that begins before the beginning of the time interval associated with the context resource, and whose end coincides with its end.",
+	RelIntervalIn:             "Refers to a resource associated with a time interval that begins before or is coincident with the beginning of the time interval associated with the context resource, and ends after or is coincident with its end.",
+	RelIntervalMeets:          "Refers to a resource associated with a time interval whose beginning coincides with the end of the time interval associated with the context resource.",
+	RelIntervalMetBy:          "Refers to a resource associated with a time interval whose end coincides with the beginning of the time interval associated with the context resource.",
+	RelIntervalOverlappedBy:   "Refers to a resource associated with a time interval that begins before the beginning of the time interval associated with the context resource, and ends after its beginning.",
+	RelIntervalOverlaps:       "Refers to a resource associated with a time interval that begins before the end of the time interval associated with the context resource, and ends after its end.",
+	RelIntervalStartedBy:      "Refers to a resource associated with a time interval whose beginning coincides with the beginning of the time interval associated with the context resource, and ends before its end.",
+	RelIntervalStarts:         "Refers to a resource associated with a time interval whose beginning coincides with the beginning of the time interval associated with the context resource, and ends after its end.",
+	RelItem:                   "The target IRI points to a resource that is a member of the collection represented by the context IRI.",
+	RelLast:                   "An IRI that refers to the furthest following resource in a series of resources.",
+	RelLatestVersion:          "Points to a resource containing the latest version.",
+	RelLicense:                "Refers to a license associated with this context.",
+	RelLinkset:                "The link target of a link with the linkset relation type provides a set of links, including links in which the link context of the link participates.",
+	RelLRDD:                   "Refers to further information about the link's context, expressed as a LRDD document.",
+	RelManifest:               "Links to a manifest file for the context.",
+	RelMaskIcon:               "Refers to a mask that can be applied to the icon for the context.",
+	RelMe:                     "Indicates that the link target is a representation of the person or entity that the link context is a representation of.",
+	RelMediaFeed:              "Refers to a feed of personalised media recommendations relevant to the link context.",
+	RelMemento:                "The Target IRI points to a Memento, a fixed resource that will not change state anymore.",
+	RelMicropub:               "Links to the context's Micropub endpoint.",
+	RelModulePreload:          "Refers to a module that the user agent is to preemptively fetch and store for use in the current context.",
+	RelMonitor:                "Refers to a resource that can be used to monitor changes in an HTTP resource.",
+	RelMonitorGroup:           "Refers to a resource that can be used to monitor changes in a specified group of HTTP resources.",
+	RelNext:                   "Indicates that the link's context is a part of a series, and that the next in the series is the link target.",
+	RelNextArchive:            "Refers to the immediately following archive resource.",
+	RelNoFollow:               "Indicates that the context's original author or publisher does not endorse the link target.",
+	RelNoOpener:               "Indicates that any newly created top-level browsing context which results from following the link will not be an auxiliary browsing context.",
+	RelNoReferrer:             "Indicates that no referrer information is to be leaked when following the link.",
+	RelOpener:                 "Indicates that any newly created top-level browsing context which results from following the link will be an auxiliary browsing context.",
+	RelOpenID2LocalID:         "Refers to an OpenID Authentication server on which the context relies for an assertion that the end user controls an Identifier.",
+	RelOpenID2Provider:        "Refers to a resource which accepts OpenID Authentication protocol messages for the context.",
+	RelOriginal:               "The Target IRI points to an Original Resource.",
+	RelP3Pv1:                  "Refers to a P3P privacy policy for the context.",
+	RelPayment:                "Indicates a resource where payment is accepted.",
+	RelPingback:               "Gives the address of the pingback resource for the link context.",
+	RelPreconnect:             "Used to indicate an origin that will be used to fetch required resources for the link context, initiating an early connection.",
+	RelPredecessorVersion:     "Points to a resource containing the predecessor version in the version history.",
+	RelPrefetch:               "The prefetch link relation type is used to identify a resource that might be required by the next navigation from the link context.",
+	RelPreload:                "Refers to a resource that should be loaded early in the processing of the link's context, without blocking rendering.",
+	RelPrerender:              "Used to identify a resource that might be required by the next navigation from the link context, and that the user agent ought to fetch and execute.",
+	RelPrev:                   "Indicates that the link's context is a part of a series, and that the previous in the series is the link target.",
+	RelPrevArchive:            "Refers to the immediately preceding archive resource.",
+	RelPreview:                "Refers to a resource that provides a preview of the link's context.",
+	RelPrevious:               "Refers to the previous resource in an ordered series of resources. Synonym for prev.",
+	RelPrivacyPolicy:          "Refers to a privacy policy associated with the link's context.",
+	RelProfile:                "Identifying that a resource representation conforms to a certain profile, without affecting the non-profile semantics of the resource representation.",
+	RelPublication:            "Links to a publication manifest.",
+	RelRelated:                "Identifies a related resource.",
+	RelReplies:                "Identifies a resource that is a reply to the context of the link.",
+	RelRestconf:               "Conveys the location of the RESTCONF API root.",
+	RelRuleInput:              "The resource identified by the link target provides an input value to an instance of a rule, where the resource which represents the rule instance is identified by the link context.",
+	RelSearch:                 "Refers to a resource that can be used to search through the link's context and related resources.",
+	RelSection:                "Refers to a section in a collection of resources.",
+	RelSelf:                   "Conveys an identifier for the link's context.",
+	RelService:                "Indicates a URI that can be used to retrieve a service document.",
+	RelServiceDesc:            "Identifies service description for the context that is primarily intended for consumption by machines.",
+	RelServiceDoc:             "Identifies service documentation for the context that is primarily intended for human consumption.",
+	RelServiceMeta:            "Identifies general metadata for the context that is primarily intended for consumption by machines.",
+	RelSIPTrunkingCapability:  "Refers to a SIP trunking capability set document for the link context.",
+	RelSponsored:              "Refers to a resource that is within a context that is sponsored.",
+	RelStart:                  "Refers to the first resource in a collection of resources.",
+	RelStatus:                 "Identifies a resource that represents the context's status.",
+	RelStylesheet:             "Refers to a stylesheet.",
+	RelSubsection:             "Refers to a resource serving as a subsection in a collection of resources.",
+	RelSuccessorVersion:       "Points to a resource containing the successor version in the version history.",
+	RelSunset:                 "Identifies a resource that provides information about the context's retirement schedule.",
+	RelTag:                    "Gives a tag that applies to the current document.",
+	RelTermsOfService:         "Refers to the terms of service associated with the link's context.",
+	RelTimeGate:               "The Target IRI points to a TimeGate for an Original Resource.",
+	RelTimeMap:                "The Target IRI points to a TimeMap for an Original Resource.",
+	RelType:                   "Refers to a resource identifying the abstract semantic type of which the link's context is considered to be an instance.",
+	RelUGC:                    "Refers to a resource that is within a context that is User Generated Content.",
+	RelUp:                     "Refers to a parent document in a hierarchy of documents.",
+	RelVersionHistory:         "Points to a resource containing the version history for the context.",
+	RelVia:                    "Identifies a resource that is the source of the information in the link's context.",
+	RelWebmention:             "Identifies a target URI that supports the Webmention protocol.",
+	RelWorkingCopy:            "Points to a working copy for this resource.",
+	RelWorkingCopyOf:          "Points to the versioned resource from which this working copy was obtained.",
+}
@@ -0,0 +1,136 @@
+package haljson
+
+import (
+	"net/url"
+	"strings"
+)
+
+// ResolveHrefs resolves every href of r and of its embedded resources
+// against base, as described by RFC 3986. Templated hrefs keep their
+// template, only the literal part before the first expression is resolved.
+// Hrefs that start with an expression are left unchanged.
+func (r *Resource[T]) ResolveHrefs(base *url.URL) error {
+	return rewriteHrefs(r, func(href string, templated bool) (string, error) {
+		return resolveHref(base, href, templated)
+	})
+}
+
+// RelativizeHrefs rewrites every absolute href of r and of its embedded
+// resources that shares the scheme and authority of base as a relative
+// reference. It reverses ResolveHrefs.
+func (r *Resource[T]) RelativizeHrefs(base *url.URL) error {
+	return rewriteHrefs(r, func(href string, templated bool) (string, error) {
+		return relativizeHref(base, href, templated)
+	})
+}
+
+// ResolveHrefs resolves every href of r and of its embedded resources
+// against base, as described by RFC 3986. Templated hrefs keep their
+// template, only the literal part before the first expression is resolved.
+// Hrefs that start with an expression are left unchanged.
+func (r *StateResource[S]) ResolveHrefs(base *url.URL) error {
+	return rewriteHrefs(r, func(href string, templated bool) (string, error) {
+		return resolveHref(base, href, templated)
+	})
+}
+
+// RelativizeHrefs rewrites every absolute href of r and of its embedded
+// resources that shares the scheme and authority of base as a relative
+// reference. It reverses ResolveHrefs.
+func (r *StateResource[S]) RelativizeHrefs(base *url.URL) error {
+	return rewriteHrefs(r, func(href string, templated bool) (string, error) {
+		return relativizeHref(base, href, templated)
+	})
+}
+
+// splitTemplate splits a templated href before its first expression
+func splitTemplate(href string, templated bool) (string, string) {
+	if templated {
+		if i := strings.IndexByte(href, '{'); i >= 0 {
+			return href[:i], href[i:]
+		}
+	}
+	return href, ""
+}
+
+// resolveHref resolves href against base
+func resolveHref(base *url.URL, href string, templated bool) (string, error) {
+	prefix, template := splitTemplate(href, templated)
+	if prefix == "" && template != "" {
+		return href, nil
+	}
+	reference, err := url.Parse(prefix)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(reference).String() + template, nil
+}
+
+// relativizeHref returns href as a reference relative to base when it has
+// the same scheme and authority. References below the directory of base
+// become relative paths, others absolute paths.
+func relativizeHref(base *url.URL, href string, templated bool) (string, error) {
+	prefix, template := splitTemplate(href, templated)
+	u, err := url.Parse(prefix)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || !strings.EqualFold(u.Scheme, base.Scheme) ||
+		!strings.EqualFold(u.Host, base.Host) || u.User.String() != base.User.String() {
+		return href, nil
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	dir := base.EscapedPath()
+	dir = dir[:strings.LastIndexByte(dir, '/')+1]
+	if dir == "" {
+		dir = "/"
+	}
+	if rest, ok := strings.CutPrefix(path, dir); ok && rest != "" && !strings.HasPrefix(rest, "/") {
+		// A first segment holding a colon would read as a scheme
+		segment, _, _ := strings.Cut(rest, "/")
+		if !strings.Contains(segment, ":") {
+			path = rest
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(path)
+	if u.ForceQuery || u.RawQuery != "" {
+		b.WriteString("?")
+		b.WriteString(u.RawQuery)
+	}
+	if u.Fragment != "" {
+		b.WriteString("#")
+		b.WriteString(u.EscapedFragment())
+	}
+	b.WriteString(template)
+	return b.String(), nil
+}
+
+// rewriteHrefs replaces every href of node and of its embedded resources
+// with the result of rewrite. Errors are reported with the JSON Pointer of
+// the href.
+func rewriteHrefs(node resourceNode, rewrite func(href string, templated bool) (string, error)) error {
+	return Walk(node, func(n *Node) error {
+		var href *string
+		var templated bool
+		switch n.Kind {
+		case LinkNode:
+			href, templated = &n.Link.Href, n.Link.Templated
+		case CurieNode:
+			href, templated = &n.Curie.Href, n.Curie.Templated
+		default:
+			return nil
+		}
+		rewritten, err := rewrite(*href, templated)
+		if err != nil {
+			return &PathError{Path: pointer(n.Path, HREF), Err: err}
+		}
+		*href = rewritten
+		return nil
+	})
+}
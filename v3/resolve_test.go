@@ -0,0 +1,116 @@
+package haljson
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveHref(t *testing.T) {
+	base, _ := url.Parse("http://example.com/api/orders/")
+
+	tests := []struct {
+		href      string
+		templated bool
+		expected  string
+	}{
+		{"/orders/1", false, "http://example.com/orders/1"},
+		{"1", false, "http://example.com/api/orders/1"},
+		{"../customers/7809", false, "http://example.com/api/customers/7809"},
+		{"?page=2", false, "http://example.com/api/orders/?page=2"},
+		{"https://other.com/x", false, "https://other.com/x"},
+		{"/orders{?page,size}", true, "http://example.com/orders{?page,size}"},
+		{"items/{id}", true, "http://example.com/api/orders/items/{id}"},
+		{"{+path}", true, "{+path}"},
+	}
+	for _, tt := range tests {
+		resolved, err := resolveHref(base, tt.href, tt.templated)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, resolved, tt.href)
+	}
+}
+
+func TestRelativizeHref(t *testing.T) {
+	base, _ := url.Parse("http://example.com/api/orders/")
+
+	tests := []struct {
+		href      string
+		templated bool
+		expected  string
+	}{
+		{"http://example.com/api/orders/1", false, "1"},
+		{"http://example.com/api/orders/1?x=y#top", false, "1?x=y#top"},
+		{"http://example.com/customers/7809", false, "/customers/7809"},
+		{"http://example.com/api/orders/", false, "/api/orders/"},
+		{"http://example.com/api/orders/a:b", false, "/api/orders/a:b"},
+		{"http://example.com", false, "/"},
+		{"https://example.com/api/orders/1", false, "https://example.com/api/orders/1"},
+		{"http://other.com/api/orders/1", false, "http://other.com/api/orders/1"},
+		{"/already/relative", false, "/already/relative"},
+		{"http://example.com/api/orders/items/{id}", true, "items/{id}"},
+	}
+	for _, tt := range tests {
+		relative, err := relativizeHref(base, tt.href, tt.templated)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, relative, tt.href)
+
+		// Relative references resolve back to the same href
+		resolved, err := resolveHref(base, relative, tt.templated)
+		assert.NoError(t, err)
+		if u, _ := url.Parse(tt.href); u.IsAbs() && u.Path != "" {
+			assert.Equal(t, tt.href, resolved, tt.href)
+		}
+	}
+}
+
+func TestResourceResolveHrefs(t *testing.T) {
+	base, _ := url.Parse("https://api.example.com/v1/")
+
+	r := NewResource[any]()
+	r.Self("orders")
+	r.AddCurie(&Curie{Name: "ea", Href: "/docs/rels/{rel}", Templated: true})
+	r.AddLink("ea:find", &Link{Href: "orders{?id}", Templated: true}, Single)
+	r.AddLink("next", &Link{Href: "orders?page=2"})
+	order := NewStateResource(map[string]int{"total": 30})
+	order.Self("orders/123")
+	r.AddEmbed("ea:order", order)
+
+	assert.NoError(t, r.ResolveHrefs(base))
+	b, err := json.Marshal(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"_links": {
+			"self": {"href": "https://api.example.com/v1/orders"},
+			"curies": [{"name": "ea", "href": "https://api.example.com/docs/rels/{rel}", "templated": true}],
+			"ea:find": {"href": "https://api.example.com/v1/orders{?id}", "templated": true},
+			"next": [{"href": "https://api.example.com/v1/orders?page=2"}]
+		},
+		"_embedded": {
+			"ea:order": [{"_links": {"self": {"href": "https://api.example.com/v1/orders/123"}}, "total": 30}]
+		}
+	}`, string(b))
+
+	assert.NoError(t, r.RelativizeHrefs(base))
+	assert.Equal(t, "orders", r.Links.Self.Href)
+	assert.Equal(t, "/docs/rels/{rel}", r.Links.Curies[0].Href)
+	assert.Equal(t, "orders{?id}", r.Links.Relations["ea:find"][0].Href)
+	assert.Equal(t, "orders?page=2", r.Links.Relations["next"][0].Href)
+	assert.Equal(t, "orders/123", order.Links.Self.Href)
+}
+
+func TestResourceResolveHrefsError(t *testing.T) {
+	base, _ := url.Parse("https://api.example.com/")
+
+	child := NewResource[any]()
+	child.AddLink("broken", &Link{Href: "%zz"})
+	r := NewResource[any]()
+	r.SetEmbed("item", child)
+
+	err := r.ResolveHrefs(base)
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_embedded/item/_links/broken/0/href", pathErr.Path)
+}
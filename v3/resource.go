@@ -1,19 +1,85 @@
 package haljson
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"sort"
-	"strings"
+	"net/url"
 )
 
+// resourceNode is implemented by resources that hold Links and Embeds
+type resourceNode interface {
+	embedsHolder
+	links() *Links
+}
+
+// adopt makes parent the Links embed inherits curies from, when embed is a
+// Resource or StateResource
+func adopt(parent *Links, embed any) {
+	var links *Links
+	switch v := embed.(type) {
+	case resourceNode:
+		links = v.links()
+	case Resource[any]:
+		links = v.Links
+	}
+	if links != nil && links != parent {
+		links.parent = parent
+	}
+}
+
+// embedRel returns the relation of embeds matching rel in either CURIE or
+// URI form, resolved with the curies in scope for links
+func embedRel(links *Links, embeds *Embeds, rel string) string {
+	if links == nil {
+		return rel
+	}
+	if key, ok := matchRel(embeds.rels(), rel, links.ExpandRel); ok {
+		return key
+	}
+	return rel
+}
+
+// addEmbed adds embed to embeds under rel, as Embeds.AddEmbed does, making
+// links the Links it inherits curies from
+func addEmbed(links *Links, embeds *Embeds, rel string, embed any) error {
+	err := embeds.AddEmbed(rel, embed)
+	if err == nil {
+		adopt(links, embed)
+	}
+	return err
+}
+
+// setSingleEmbed sets embed as the single embedded resource of rel, as
+// Embeds.SetEmbed does, making links the Links it inherits curies from
+func setSingleEmbed(links *Links, embeds *Embeds, rel string, embed any) error {
+	err := embeds.SetEmbed(rel, embed)
+	if err == nil {
+		adopt(links, embed)
+	}
+	return err
+}
+
+// replaceEmbeds replaces the embedded resources of rel, given in either
+// CURIE or URI form, making links the Links they inherit curies from
+func replaceEmbeds(links *Links, embeds *Embeds, rel string, replacements []any) error {
+	err := embeds.Replace(embedRel(links, embeds, rel), replacements...)
+	if err != nil {
+		return err
+	}
+	for _, embed := range replacements {
+		adopt(links, embed)
+	}
+	return nil
+}
+
 // Resource represents a Resource with Links and Embeds with Data
 type Resource[T any] struct {
 	Links  *Links  `json:"_links,omitempty"`
 	Embeds *Embeds `json:"_embedded,omitempty"`
 	// When serializing to JSON we need to handle this specially
 	Data map[string]T `json:"-"`
+	// order records data keys in the order they were set
+	order []string
 }
 
 // Self is used to add a self link
@@ -21,18 +87,143 @@ func (r *Resource[T]) Self(uri string) {
 	r.Links.Self = &Link{Href: uri}
 }
 
-// AddLink adds a link to reltype
-func (r *Resource[T]) AddLink(reltype string, link *Link) error {
-	return r.Links.AddLink(reltype, link)
+// AddLink adds a link to reltype, optionally forcing its Cardinality. A
+// CURIE reltype may use curies declared once the resource is embedded, as
+// Links.AddLink describes.
+func (r *Resource[T]) AddLink(reltype string, link *Link, cardinality ...Cardinality) error {
+	return r.Links.AddLink(reltype, link, cardinality...)
 }
 
-// AddEmbed adds a Resource by reltype
-func (r *Resource[T]) AddEmbed(reltype string, embed *Resource[any]) error {
-	if _, ok := r.Embeds.Relations[reltype]; !ok {
-		r.Embeds.Relations[reltype] = []Resource[any]{}
-	}
-	r.Embeds.Relations[reltype] = append(r.Embeds.Relations[reltype], *embed)
-	return nil
+// AddEmbed adds an embedded resource by reltype. embed may be a
+// *Resource[any], a Resource or StateResource of any type, a struct with
+// `hal` tags or any other value that marshals as a Resource Object. An
+// embedded Resource or StateResource inherits the curies of r.
+func (r *Resource[T]) AddEmbed(reltype string, embed any) error {
+	return addEmbed(r.Links, r.Embeds, reltype, embed)
+}
+
+// SetEmbed sets a single embedded resource for reltype, replacing any existing embeds.
+// The relation is represented as a single Resource Object rather than an array.
+func (r *Resource[T]) SetEmbed(reltype string, embed any) error {
+	return setSingleEmbed(r.Links, r.Embeds, reltype, embed)
+}
+
+// embeds returns the Embeds of the resource
+func (r *Resource[T]) embeds() *Embeds {
+	return r.Embeds
+}
+
+// links returns the Links of the resource
+func (r *Resource[T]) links() *Links {
+	return r.Links
+}
+
+// ExpandCuries rewrites every CURIE relation name in _links and _embedded,
+// including those of embedded resources, into the full URI of the relation.
+// Embedded resources may use the curies of the resources embedding them.
+func (r *Resource[T]) ExpandCuries() {
+	rewriteRels(r, r.Links.parent.curieScope(), expandRel)
+}
+
+// CompactCuries rewrites every full URI relation name in _links and
+// _embedded, including those of embedded resources, into a CURIE when a
+// curie in scope matches it. It reverses ExpandCuries.
+func (r *Resource[T]) CompactCuries() {
+	rewriteRels(r, r.Links.parent.curieScope(), compactRel)
+}
+
+// GetLinks returns the links of rel, as Links.Get does
+func (r *Resource[T]) GetLinks(rel string) []*Link {
+	return r.Links.Get(rel)
+}
+
+// FirstLink returns the first link of rel, or nil when there is none
+func (r *Resource[T]) FirstLink(rel string) *Link {
+	return r.Links.First(rel)
+}
+
+// LinkByName returns the link of rel with the given name, or nil when there is none
+func (r *Resource[T]) LinkByName(rel string, name string) *Link {
+	return r.Links.ByName(rel, name)
+}
+
+// HasLink reports whether rel holds any links
+func (r *Resource[T]) HasLink(rel string) bool {
+	return r.Links.Has(rel)
+}
+
+// RemoveLink removes every link of rel and reports whether there were any
+func (r *Resource[T]) RemoveLink(rel string) bool {
+	return r.Links.Remove(rel)
+}
+
+// ReplaceLinks replaces the links of rel, as Links.Replace does
+func (r *Resource[T]) ReplaceLinks(rel string, links ...*Link) error {
+	return r.Links.Replace(rel, links...)
+}
+
+// LinkRels returns the relations holding links in sorted order
+func (r *Resource[T]) LinkRels() []string {
+	return r.Links.Rels()
+}
+
+// RangeLinks calls fn for every link, as Links.Range does
+func (r *Resource[T]) RangeLinks(fn func(rel string, link *Link) bool) {
+	r.Links.Range(fn)
+}
+
+// GetEmbeds returns the embedded resources of rel, as Embeds.Get does. rel
+// may be given as a CURIE or as the full URI it expands to.
+func (r *Resource[T]) GetEmbeds(rel string) []any {
+	return r.Embeds.Get(embedRel(r.Links, r.Embeds, rel))
+}
+
+// FirstEmbed returns the first embedded resource of rel, or nil when there is none
+func (r *Resource[T]) FirstEmbed(rel string) any {
+	return r.Embeds.First(embedRel(r.Links, r.Embeds, rel))
+}
+
+// EmbedByName returns the embedded resource of rel whose self link has the
+// given name, or nil when there is none
+func (r *Resource[T]) EmbedByName(rel string, name string) any {
+	return r.Embeds.ByName(embedRel(r.Links, r.Embeds, rel), name)
+}
+
+// HasEmbed reports whether rel holds any embedded resources
+func (r *Resource[T]) HasEmbed(rel string) bool {
+	return r.Embeds.Has(embedRel(r.Links, r.Embeds, rel))
+}
+
+// RemoveEmbed removes every embedded resource of rel and reports whether there were any
+func (r *Resource[T]) RemoveEmbed(rel string) bool {
+	return r.Embeds.Remove(embedRel(r.Links, r.Embeds, rel))
+}
+
+// ReplaceEmbeds replaces the embedded resources of rel, as Embeds.Replace
+// does. Embedded Resources and StateResources inherit the curies of r.
+func (r *Resource[T]) ReplaceEmbeds(rel string, embeds ...any) error {
+	return replaceEmbeds(r.Links, r.Embeds, rel, embeds)
+}
+
+// EmbedRels returns the relations holding embedded resources in sorted order
+func (r *Resource[T]) EmbedRels() []string {
+	return r.Embeds.Rels()
+}
+
+// RangeEmbeds calls fn for every embedded resource, as Embeds.Range does
+func (r *Resource[T]) RangeEmbeds(fn func(rel string, embed any) bool) {
+	r.Embeds.Range(fn)
+}
+
+// SetRegistry restricts the relation types of the resource, and of the
+// resources it embeds, to those registered in registry, as Links.SetRegistry does
+func (r *Resource[T]) SetRegistry(registry *Registry) {
+	r.Links.SetRegistry(registry)
+}
+
+// Paginate writes the pagination links of the resource, as Links.Paginate does
+func (r *Resource[T]) Paginate(base *url.URL, paging Paging) error {
+	return r.Links.Paginate(base, paging)
 }
 
 // AddCurie adds a curie to the links
@@ -40,130 +231,181 @@ func (r *Resource[T]) AddCurie(curie *Curie) error {
 	return r.Links.AddCurie(curie)
 }
 
+// Set sets a data property, recording its insertion order
+func (r *Resource[T]) Set(key string, value T) {
+	r.Data[key] = value
+	r.order = appendOrder(r.order, key)
+}
+
 // MarshalJSON marshals a resource properly
 func (r *Resource[T]) MarshalJSON() ([]byte, error) {
-	// Marshal links
-	var links *string
-	if r.Links != nil && (len(r.Links.Relations) > 0 || r.Links.Self != nil) {
-		b, err := json.Marshal(r.Links)
-		if err != nil {
-			return nil, err
+	return marshalPooled(r, defaultEncodeOptions)
+}
+
+// AppendJSON appends the encoding of r to dst and returns the extended
+// buffer, as MarshalJSON does without allocating a new one. The whole
+// document, embedded resources included, is written into dst.
+func (r *Resource[T]) AppendJSON(dst []byte) ([]byte, error) {
+	return r.appendHAL(dst, defaultEncodeOptions)
+}
+
+// appendHAL appends a resource to dst using the given options
+func (r *Resource[T]) appendHAL(dst []byte, opts *encodeOptions) ([]byte, error) {
+	// Data keys reserved by HAL would be written twice, they are rejected or
+	// merged into their section as opts requires
+	if opts.reserved != MergeReservedKeys {
+		for _, key := range [...]string{LINKS, EMBEDDED} {
+			if _, ok := r.Data[key]; ok {
+				return nil, &PathError{Path: pointer("", key), Err: ErrReservedKey}
+			}
 		}
-		linkString := fmt.Sprintf("\"%s\": %s", LINKS, string(b))
-		links = &linkString
 	}
 
-	// Marshal Embeds
-	var embeds *string
-	if r.Embeds != nil && len(r.Embeds.Relations) > 0 {
-		b, err := json.Marshal(r.Embeds)
-		if err != nil {
-			return nil, err
+	// The resource the encoder started from writes the streamed relations
+	stream := opts.stream
+	if stream != nil && !stream.started {
+		stream.started = true
+		if _, ok := r.Data[EMBEDDED]; ok {
+			return nil, &PathError{Path: pointer("", EMBEDDED), Err: ErrReservedKey}
 		}
-		embedString := fmt.Sprintf("\"%s\": %s", EMBEDDED, string(b))
-		embeds = &embedString
+	} else {
+		stream = nil
 	}
 
-	// Sort keys for deterministic output (required for consistent testing and comparison)
-	sortedKeys := make([]string, 0, len(r.Data))
-	for k := range r.Data {
-		sortedKeys = append(sortedKeys, k)
+	dst = append(dst, '{')
+	var err error
+	hasLinks := r.Links != nil && !r.Links.isEmpty(opts)
+	dst, err = appendSection(dst, LINKS, r.Links, hasLinks, r.Data, opts)
+	if err != nil {
+		return nil, err
+	}
+	hasEmbeds := r.Embeds != nil && (len(r.Embeds.Relations) > 0 || len(r.Embeds.Values) > 0)
+	if stream != nil && (hasEmbeds || len(stream.producers) > 0) {
+		dst, _ = appendKey(dst, EMBEDDED)
+		dst, err = stream.appendEmbeds(dst, r.Embeds, opts)
+	} else {
+		dst, err = appendSection(dst, EMBEDDED, r.Embeds, hasEmbeds, r.Data, opts)
+	}
+	if err != nil {
+		return nil, err
 	}
-	sort.Strings(sortedKeys)
 
-	// Marshal the data
-	var dataBuffer []string
-	for _, key := range sortedKeys {
-		b, err := json.Marshal(r.Data[key])
+	// Append the data, ordered for deterministic output (required for consistent testing and comparison)
+	for _, key := range orderedKeys(r.Data, r.order, opts) {
+		if key == LINKS || key == EMBEDDED {
+			continue
+		}
+		dst, err = appendKey(dst, key)
+		if err != nil {
+			return nil, err
+		}
+		dst, err = appendValue(dst, r.Data[key], opts)
 		if err != nil {
 			return nil, err
 		}
-		dataBuffer = append(dataBuffer, fmt.Sprintf("\"%s\": %s", key, string(b)))
 	}
+	return append(dst, '}'), nil
+}
 
-	// Produce JSON
-	var joined []string
-
-	buffer := bytes.NewBufferString("{")
-
-	if links != nil {
-		joined = append(joined, *links)
+// appendSection appends the HAL section key of a resource to dst. A Data
+// member with the same key, which has been let through by opts, is merged
+// into the section.
+func appendSection[T any](dst []byte, key string, section halEncoder, present bool, data map[string]T, opts *encodeOptions) ([]byte, error) {
+	value, reserved := data[key]
+	if !present && !reserved {
+		return dst, nil
 	}
-
-	if embeds != nil {
-		joined = append(joined, *embeds)
+	dst, _ = appendKey(dst, key)
+	if !reserved {
+		return section.appendHAL(dst, opts)
 	}
 
-	if len(dataBuffer) > 0 {
-		joined = append(joined, strings.Join(dataBuffer, ","))
+	var sectionJSON []byte
+	var err error
+	if present {
+		sectionJSON, err = section.appendHAL(nil, opts)
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	buffer.WriteString(strings.Join(joined, ","))
-	buffer.WriteString("}")
-
-	return buffer.Bytes(), nil
+	extra, err := marshalValue(value, opts)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergeObject(sectionJSON, extra)
+	if err != nil {
+		return nil, &PathError{Path: pointer("", key), Err: fmt.Errorf("%w: %w", ErrReservedKey, err)}
+	}
+	return append(dst, merged...), nil
 }
 
 // UnmarshalJSON unmarshals a Resource from JSON
 func (r *Resource[T]) UnmarshalJSON(b []byte) error {
-	temp := make(map[string]any)
-	err := json.Unmarshal(b, &temp)
-	if err != nil {
-		return err
-	}
+	return r.unmarshalHAL(b, newDecodeOptions(), "")
+}
 
-	embedded := NewEmbeds()
+// unmarshalHAL unmarshals a Resource found at path using the given options
+func (r *Resource[T]) unmarshalHAL(b []byte, opts *decodeOptions, path string) error {
+	_, err := r.unmarshalExcept(b, opts, path, "")
+	return err
+}
 
-	if _, ok := temp[EMBEDDED]; ok {
-		// re marshal embedded and links
-		embeddedjson, err := json.Marshal(temp[EMBEDDED])
-		if err != nil {
-			return err
-		}
-		err = json.Unmarshal(embeddedjson, &embedded)
-		if err != nil {
-			return err
-		}
+// unmarshalExcept unmarshals a resource found at path as unmarshalHAL does,
+// leaving the embedded relation except out of Embeds. It returns the raw
+// value of except, nil when there is none.
+func (r *Resource[T]) unmarshalExcept(b []byte, opts *decodeOptions, path string, except string) (json.RawMessage, error) {
+	// Members are kept raw, each one is decoded from its own bytes rather
+	// than being decoded as any and marshaled again
+	temp := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &temp)
+	if err != nil {
+		return nil, err
 	}
-	r.Embeds = embedded
-	delete(temp, EMBEDDED)
 
 	links := NewLinks()
-	if _, ok := temp[LINKS]; ok {
-		linksjson, err := json.Marshal(temp[LINKS])
+	links.parent = opts.parent
+	if linksjson, ok := temp[LINKS]; ok {
+		err = links.unmarshalHAL(linksjson, opts, pointer(path, LINKS))
 		if err != nil {
-			return err
-		}
-		err = json.Unmarshal(linksjson, &links)
-		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	r.Links = links
 	delete(temp, LINKS)
 
-	// Convert remaining data fields from any to T
-	// Note: Uses marshal/unmarshal for type conversion to ensure type safety.
-	// This provides robust handling of complex types at the cost of performance.
-	// For T=any, the marshal/unmarshal is overhead but maintains consistency.
-	r.Data = make(map[string]T)
-	for k, v := range temp {
-		data, err := json.Marshal(v)
-		if err != nil {
+	embedded := NewEmbeds()
+	var excepted json.RawMessage
+	if embeddedjson, ok := temp[EMBEDDED]; ok {
+		// Embedded resources inherit the curies of links
+		err = opts.within(links, func() error {
+			excepted, err = embedded.unmarshalExcept(embeddedjson, opts, pointer(path, EMBEDDED), except)
 			return err
+		})
+		if err != nil {
+			return nil, err
 		}
+	}
+	r.Embeds = embedded
+	delete(temp, EMBEDDED)
+
+	// Decode the remaining data fields as T
+	r.Data = make(map[string]T, len(temp))
+	// Document order is not available here, decoded keys are written sorted
+	r.order = nil
+	for k, data := range temp {
 		var typedValue T
 		err = json.Unmarshal(data, &typedValue)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		r.Data[k] = typedValue
 	}
-	return nil
+	return excepted, nil
 }
 
-// NewResource creates a Resource and initializes it
+// NewResource creates a Resource and initializes it. Its links accept any
+// relation type, NewValidatedResource checks them.
 func NewResource[T any]() *Resource[T] {
 	return &Resource[T]{
 		Data:   make(map[string]T),
@@ -171,3 +413,22 @@ func NewResource[T any]() *Resource[T] {
 		Embeds: NewEmbeds(),
 	}
 }
+
+// NewValidatedResource creates a Resource whose links only accept the
+// relation types of registry, absolute URIs and CURIEs, as SetRegistry
+// does. A nil registry stands for IANA(). This is the recommended way to
+// create a resource, a misspelled relation type such as "nxet" is rejected
+// by AddLink rather than written out.
+func NewValidatedResource[T any](registry *Registry) *Resource[T] {
+	r := NewResource[T]()
+	r.SetRegistry(validatingRegistry(registry))
+	return r
+}
+
+// validatingRegistry returns registry, or IANA when it is nil
+func validatingRegistry(registry *Registry) *Registry {
+	if registry == nil {
+		return iana
+	}
+	return registry
+}
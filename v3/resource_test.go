@@ -274,3 +274,69 @@ func TestResourceMarshalErrors(t *testing.T) {
 	_, err = json.Marshal(r2)
 	assert.Nil(t, err)
 }
+
+func TestResourceSetEmbed(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/orders/1")
+
+	customer := NewResource[any]()
+	customer.Self("/customers/1")
+	assert.Nil(t, r.SetEmbed("customer", customer))
+
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders/1"}},"_embedded":{"customer":{"_links":{"self":{"href":"/customers/1"}}}}}`, string(b))
+
+	var inflated Resource[any]
+	assert.Nil(t, json.Unmarshal(b, &inflated))
+	assert.Equal(t, Single, inflated.Embeds.Cardinality("customer"))
+	b2, err := json.Marshal(&inflated)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), string(b2))
+
+	// Adding a second embed turns the relation into an array
+	assert.Nil(t, r.AddEmbed("customer", NewResource[any]()))
+	assert.Equal(t, Many, r.Embeds.Cardinality("customer"))
+	assert.Len(t, r.Embeds.Relations["customer"], 2)
+}
+
+func TestResourceNavigation(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/orders")
+	r.AddCurie(&Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true})
+	r.AddLink("ea:admin", &Link{Href: "/admins/2", Name: "v2"})
+	order := NewResource[any]()
+	r.AddEmbed("ea:order", order)
+
+	assert.Equal(t, "/orders", r.FirstLink("self").Href)
+	assert.Equal(t, "/admins/2", r.LinkByName("ea:admin", "v2").Href)
+	assert.Len(t, r.GetLinks("http://example.com/docs/rels/admin"), 1)
+	assert.True(t, r.HasLink("ea:admin"))
+	assert.Equal(t, []string{"ea:admin"}, r.LinkRels())
+
+	assert.True(t, r.HasEmbed("http://example.com/docs/rels/order"))
+	assert.Equal(t, []string{"ea:order"}, r.EmbedRels())
+	assert.Len(t, r.GetEmbeds("ea:order"), 1)
+
+	replacement := NewResource[any]()
+	assert.Nil(t, r.ReplaceEmbeds("http://example.com/docs/rels/order", replacement))
+	assert.Equal(t, []string{"ea:order"}, r.EmbedRels())
+	assert.Nil(t, replacement.AddLink("ea:customer", &Link{Href: "/customers/1"}), "replacements inherit curies")
+
+	assert.Nil(t, r.ReplaceLinks("ea:admin", &Link{Href: "/admins/3"}))
+	assert.Equal(t, "/admins/3", r.FirstLink("ea:admin").Href)
+	assert.True(t, r.RemoveLink("ea:admin"))
+	assert.True(t, r.RemoveEmbed("ea:order"))
+	assert.False(t, r.HasEmbed("ea:order"))
+
+	count := 0
+	r.RangeLinks(func(rel string, link *Link) bool {
+		count++
+		return true
+	})
+	r.RangeEmbeds(func(rel string, embed any) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,250 @@
+package haljson
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// StateResource represents a Resource whose state is a single value of type
+// S, usually a struct, marshaled inline next to _links and _embedded. This
+// lets properties of different types keep their own Go types.
+type StateResource[S any] struct {
+	Links  *Links  `json:"_links,omitempty"`
+	Embeds *Embeds `json:"_embedded,omitempty"`
+	// When serializing to JSON we need to handle this specially
+	State S `json:"-"`
+}
+
+// Self is used to add a self link
+func (r *StateResource[S]) Self(uri string) {
+	r.Links.Self = &Link{Href: uri}
+}
+
+// AddLink adds a link to reltype, optionally forcing its Cardinality. A
+// CURIE reltype may use curies declared once the resource is embedded, as
+// Links.AddLink describes.
+func (r *StateResource[S]) AddLink(reltype string, link *Link, cardinality ...Cardinality) error {
+	return r.Links.AddLink(reltype, link, cardinality...)
+}
+
+// AddEmbed adds an embedded resource by reltype. embed may be a
+// *Resource[any], a Resource or StateResource of any type, a struct with
+// `hal` tags or any other value that marshals as a Resource Object. An
+// embedded Resource or StateResource inherits the curies of r.
+func (r *StateResource[S]) AddEmbed(reltype string, embed any) error {
+	return addEmbed(r.Links, r.Embeds, reltype, embed)
+}
+
+// SetEmbed sets a single embedded resource for reltype, replacing any existing embeds.
+// The relation is represented as a single Resource Object rather than an array.
+func (r *StateResource[S]) SetEmbed(reltype string, embed any) error {
+	return setSingleEmbed(r.Links, r.Embeds, reltype, embed)
+}
+
+// embeds returns the Embeds of the resource
+func (r *StateResource[S]) embeds() *Embeds {
+	return r.Embeds
+}
+
+// links returns the Links of the resource
+func (r *StateResource[S]) links() *Links {
+	return r.Links
+}
+
+// ExpandCuries rewrites every CURIE relation name in _links and _embedded,
+// including those of embedded resources, into the full URI of the relation.
+// Embedded resources may use the curies of the resources embedding them.
+func (r *StateResource[S]) ExpandCuries() {
+	rewriteRels(r, r.Links.parent.curieScope(), expandRel)
+}
+
+// CompactCuries rewrites every full URI relation name in _links and
+// _embedded, including those of embedded resources, into a CURIE when a
+// curie in scope matches it. It reverses ExpandCuries.
+func (r *StateResource[S]) CompactCuries() {
+	rewriteRels(r, r.Links.parent.curieScope(), compactRel)
+}
+
+// GetLinks returns the links of rel, as Links.Get does
+func (r *StateResource[S]) GetLinks(rel string) []*Link {
+	return r.Links.Get(rel)
+}
+
+// FirstLink returns the first link of rel, or nil when there is none
+func (r *StateResource[S]) FirstLink(rel string) *Link {
+	return r.Links.First(rel)
+}
+
+// LinkByName returns the link of rel with the given name, or nil when there is none
+func (r *StateResource[S]) LinkByName(rel string, name string) *Link {
+	return r.Links.ByName(rel, name)
+}
+
+// HasLink reports whether rel holds any links
+func (r *StateResource[S]) HasLink(rel string) bool {
+	return r.Links.Has(rel)
+}
+
+// RemoveLink removes every link of rel and reports whether there were any
+func (r *StateResource[S]) RemoveLink(rel string) bool {
+	return r.Links.Remove(rel)
+}
+
+// ReplaceLinks replaces the links of rel, as Links.Replace does
+func (r *StateResource[S]) ReplaceLinks(rel string, links ...*Link) error {
+	return r.Links.Replace(rel, links...)
+}
+
+// LinkRels returns the relations holding links in sorted order
+func (r *StateResource[S]) LinkRels() []string {
+	return r.Links.Rels()
+}
+
+// RangeLinks calls fn for every link, as Links.Range does
+func (r *StateResource[S]) RangeLinks(fn func(rel string, link *Link) bool) {
+	r.Links.Range(fn)
+}
+
+// GetEmbeds returns the embedded resources of rel, as Embeds.Get does. rel
+// may be given as a CURIE or as the full URI it expands to.
+func (r *StateResource[S]) GetEmbeds(rel string) []any {
+	return r.Embeds.Get(embedRel(r.Links, r.Embeds, rel))
+}
+
+// FirstEmbed returns the first embedded resource of rel, or nil when there is none
+func (r *StateResource[S]) FirstEmbed(rel string) any {
+	return r.Embeds.First(embedRel(r.Links, r.Embeds, rel))
+}
+
+// EmbedByName returns the embedded resource of rel whose self link has the
+// given name, or nil when there is none
+func (r *StateResource[S]) EmbedByName(rel string, name string) any {
+	return r.Embeds.ByName(embedRel(r.Links, r.Embeds, rel), name)
+}
+
+// HasEmbed reports whether rel holds any embedded resources
+func (r *StateResource[S]) HasEmbed(rel string) bool {
+	return r.Embeds.Has(embedRel(r.Links, r.Embeds, rel))
+}
+
+// RemoveEmbed removes every embedded resource of rel and reports whether there were any
+func (r *StateResource[S]) RemoveEmbed(rel string) bool {
+	return r.Embeds.Remove(embedRel(r.Links, r.Embeds, rel))
+}
+
+// ReplaceEmbeds replaces the embedded resources of rel, as Embeds.Replace
+// does. Embedded Resources and StateResources inherit the curies of r.
+func (r *StateResource[S]) ReplaceEmbeds(rel string, embeds ...any) error {
+	return replaceEmbeds(r.Links, r.Embeds, rel, embeds)
+}
+
+// EmbedRels returns the relations holding embedded resources in sorted order
+func (r *StateResource[S]) EmbedRels() []string {
+	return r.Embeds.Rels()
+}
+
+// RangeEmbeds calls fn for every embedded resource, as Embeds.Range does
+func (r *StateResource[S]) RangeEmbeds(fn func(rel string, embed any) bool) {
+	r.Embeds.Range(fn)
+}
+
+// SetRegistry restricts the relation types of the resource, and of the
+// resources it embeds, to those registered in registry, as Links.SetRegistry does
+func (r *StateResource[S]) SetRegistry(registry *Registry) {
+	r.Links.SetRegistry(registry)
+}
+
+// Paginate writes the pagination links of the resource, as Links.Paginate does
+func (r *StateResource[S]) Paginate(base *url.URL, paging Paging) error {
+	return r.Links.Paginate(base, paging)
+}
+
+// AddCurie adds a curie to the links
+func (r *StateResource[S]) AddCurie(curie *Curie) error {
+	return r.Links.AddCurie(curie)
+}
+
+// MarshalJSON marshals the state inline with the links and embeds
+func (r *StateResource[S]) MarshalJSON() ([]byte, error) {
+	return marshalPooled(r, defaultEncodeOptions)
+}
+
+// AppendJSON appends the encoding of r to dst and returns the extended
+// buffer, as MarshalJSON does without allocating a new one
+func (r *StateResource[S]) AppendJSON(dst []byte) ([]byte, error) {
+	return r.appendHAL(dst, defaultEncodeOptions)
+}
+
+// appendHAL appends the resource to dst using the given options
+func (r *StateResource[S]) appendHAL(dst []byte, opts *encodeOptions) ([]byte, error) {
+	b, err := json.Marshal(r.State)
+	if err != nil {
+		return nil, err
+	}
+	members, err := splitObject(b)
+	if err != nil {
+		return nil, fmt.Errorf("state must marshal as a JSON object: %w", err)
+	}
+	// Reuse Resource by holding the already encoded state properties as Data
+	res := &Resource[json.RawMessage]{
+		Links:  r.Links,
+		Embeds: r.Embeds,
+		Data:   make(map[string]json.RawMessage, len(members)),
+	}
+	// Reserved keys are rejected or merged by Resource
+	for _, m := range members {
+		res.Set(m.key, m.value)
+	}
+	return res.appendHAL(dst, opts)
+}
+
+// UnmarshalJSON unmarshals the links, embeds and state
+func (r *StateResource[S]) UnmarshalJSON(b []byte) error {
+	return r.unmarshalHAL(b, newDecodeOptions(), "")
+}
+
+// unmarshalHAL unmarshals a resource found at path using the given options
+func (r *StateResource[S]) unmarshalHAL(b []byte, opts *decodeOptions, path string) error {
+	linksData, embeddedData, state, err := splitDocument(b)
+	if err != nil {
+		return err
+	}
+	r.Links = NewLinks()
+	r.Links.parent = opts.parent
+	if linksData != nil {
+		err = r.Links.unmarshalHAL(linksData, opts, pointer(path, LINKS))
+		if err != nil {
+			return err
+		}
+	}
+	r.Embeds = NewEmbeds()
+	if embeddedData != nil {
+		err = opts.within(r.Links, func() error {
+			return r.Embeds.unmarshalHAL(embeddedData, opts, pointer(path, EMBEDDED))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(state, &r.State)
+}
+
+// NewStateResource creates a StateResource holding state and initializes
+// it. Its links accept any relation type, NewValidatedStateResource checks them.
+func NewStateResource[S any](state S) *StateResource[S] {
+	return &StateResource[S]{
+		Links:  NewLinks(),
+		Embeds: NewEmbeds(),
+		State:  state,
+	}
+}
+
+// NewValidatedStateResource creates a StateResource holding state whose
+// links only accept the relation types of registry, as NewValidatedResource
+// does. A nil registry stands for IANA().
+func NewValidatedStateResource[S any](state S, registry *Registry) *StateResource[S] {
+	r := NewStateResource(state)
+	r.SetRegistry(validatingRegistry(registry))
+	return r
+}
@@ -0,0 +1,98 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testOrderState struct {
+	Currency string   `json:"currency"`
+	Status   string   `json:"status"`
+	Total    float64  `json:"total"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+func TestStateResource(t *testing.T) {
+	r := NewStateResource(testOrderState{Currency: "USD", Status: "shipped", Total: 10.20})
+	assert.Equal(t, NewLinks(), r.Links)
+	assert.Equal(t, NewEmbeds(), r.Embeds)
+	assert.Equal(t, "USD", r.State.Currency)
+}
+
+func TestStateResourceMarshal(t *testing.T) {
+	r := NewStateResource(testOrderState{Currency: "USD", Status: "shipped", Total: 10.20})
+	r.Self("/orders/523")
+	r.AddCurie(&Curie{Name: "ea", Href: "/docs/{rel}", Templated: true})
+	assert.Nil(t, r.AddLink("ea:warehouse", &Link{Href: "/warehouse/56"}, Single))
+	customer := NewResource[any]()
+	customer.Self("/customers/1")
+	assert.Nil(t, r.SetEmbed("ea:customer", customer))
+
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders/523"},"curies":[{"name":"ea","href":"/docs/{rel}","templated":true}],"ea:warehouse":{"href":"/warehouse/56"}},`+
+		`"_embedded":{"ea:customer":{"_links":{"self":{"href":"/customers/1"}}}},"currency":"USD","status":"shipped","total":10.2}`, string(b))
+
+	var buffer bytes.Buffer
+	assert.Nil(t, NewEncoder(&buffer).SetKeyOrder(InsertionOrder).Encode(NewStateResource(testOrderState{Total: 1, Currency: "EUR"})))
+	assert.Equal(t, `{"currency":"EUR","status":"","total":1}`+"\n", buffer.String())
+}
+
+func TestStateResourceUnmarshal(t *testing.T) {
+	doc := `{"_links":{"self":{"href":"/orders/523"},"ea:items":[{"href":"/items/1"}]},"_embedded":{"ea:customer":{"name":"Jane"}},"currency":"USD","status":"shipped","total":10.2,"tags":["a"]}`
+
+	var r StateResource[testOrderState]
+	err := json.Unmarshal([]byte(doc), &r)
+	assert.Nil(t, err)
+	assert.Equal(t, testOrderState{Currency: "USD", Status: "shipped", Total: 10.2, Tags: []string{"a"}}, r.State)
+	assert.Equal(t, "/orders/523", r.Links.Self.Href)
+	assert.Equal(t, "/items/1", r.Links.Relations["ea:items"][0].Href)
+	assert.Equal(t, "Jane", r.Embeds.Relations["ea:customer"][0].Data["name"])
+
+	b, err := json.Marshal(&r)
+	assert.Nil(t, err)
+	var again StateResource[testOrderState]
+	assert.Nil(t, json.Unmarshal(b, &again))
+	assert.Equal(t, r.State, again.State)
+
+	// Strict decoding applies to the links
+	var strict StateResource[testOrderState]
+	err = NewDecoder(bytes.NewReader([]byte(doc))).Strict().Decode(&strict)
+	assert.True(t, errors.Is(err, ErrNoCurie))
+	assert.Equal(t, "USD", strict.State.Currency)
+}
+
+func TestStateResourceReservedKeys(t *testing.T) {
+	type Colliding struct {
+		Links string `json:"_links"`
+	}
+	_, err := json.Marshal(NewStateResource(Colliding{Links: "oops"}))
+	assert.True(t, errors.Is(err, ErrReservedKey))
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_links", pathErr.Path)
+
+	_, err = json.Marshal(NewStateResource(map[string]int{EMBEDDED: 1}))
+	assert.True(t, errors.Is(err, ErrReservedKey))
+}
+
+func TestStateResourceErrors(t *testing.T) {
+	_, err := json.Marshal(NewStateResource(42))
+	assert.NotNil(t, err)
+
+	_, err = json.Marshal(NewStateResource(make(chan int)))
+	assert.NotNil(t, err)
+
+	var r StateResource[testOrderState]
+	assert.NotNil(t, json.Unmarshal([]byte(`{"total":"many"}`), &r))
+	assert.NotNil(t, json.Unmarshal([]byte(`[]`), &r))
+
+	// A nil state writes no properties
+	b, err := json.Marshal(NewStateResource[*testOrderState](nil))
+	assert.Nil(t, err)
+	assert.Equal(t, `{}`, string(b))
+}
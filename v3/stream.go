@@ -0,0 +1,196 @@
+package haljson
+
+import (
+	"io"
+	"strconv"
+)
+
+// EmbedProducer produces the items of a relation streamed by
+// Encoder.EncodeStream. It calls yield with each item in turn and stops at
+// the first error yield returns, returning it.
+type EmbedProducer func(yield func(item any) error) error
+
+// EmbedChannel returns an EmbedProducer embedding the items received from
+// items until it is closed. When encoding fails the producer stops
+// receiving, senders must then be stopped by other means, such as a
+// context.
+func EmbedChannel[T any](items <-chan T) EmbedProducer {
+	return func(yield func(item any) error) error {
+		for item := range items {
+			err := yield(item)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// EncodeStream writes the HAL encoding of the resource v followed by a
+// newline, embedding the items of every producer under its relation as
+// they are produced. Links are written before the first item is produced,
+// each item is written as soon as it is encoded and the state follows the
+// embedded resources, so the output is the same as Encode's for a resource
+// holding every item. Items are appended to the resources v already embeds
+// under the same relation. A relation made Single, by SetEmbed for
+// instance, is written as an object while it holds one item, so its first
+// item is held back until a second one is produced or the producer returns.
+// A Collection counts the items streamed under its relation.
+//
+// Output written before an error, from a producer, an item or the
+// underlying writer, is not taken back. Errors with an item are reported
+// as a *PathError. Indentation cannot be applied to a stream, EncodeStream
+// fails with ErrStreamIndent when it is set.
+func (enc *Encoder) EncodeStream(v any, producers map[string]EmbedProducer) error {
+	if enc.prefix != "" || enc.indent != "" {
+		return ErrStreamIndent
+	}
+	node, err := documentNode(v)
+	if err != nil {
+		return err
+	}
+	if node == nil {
+		return ErrNotResource
+	}
+	for rel := range producers {
+		_, err = appendKey(nil, rel)
+		if err != nil {
+			return err
+		}
+	}
+	return enc.encode(v, &embedStream{w: enc.w, producers: producers})
+}
+
+// embedStream writes the embedded resources of the resource EncodeStream
+// was given, streaming the items of its producers
+type embedStream struct {
+	w         io.Writer
+	producers map[string]EmbedProducer
+	// started is set once the resource streaming the items has been found
+	started bool
+	// counts are the number of items written for each streamed relation
+	counts map[string]int
+}
+
+// appendEmbeds appends the _embedded section to dst, writing dst out and
+// then each streamed item as it is produced
+func (s *embedStream) appendEmbeds(dst []byte, e *Embeds, opts *encodeOptions) ([]byte, error) {
+	if e == nil {
+		e = NewEmbeds()
+	}
+	rels := e.rels()
+	for rel := range s.producers {
+		rels[rel] = true
+	}
+
+	s.counts = make(map[string]int, len(s.producers))
+	dst = append(dst, '{')
+	var err error
+	for _, rel := range orderedKeys(rels, e.order, opts) {
+		produce, ok := s.producers[rel]
+		if !ok {
+			dst, err = e.appendRel(dst, rel, opts)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		dst, _ = appendKey(dst, rel)
+		i := e.count(rel)
+		// A single relation holding at most one item may remain an object,
+		// its item is held in first until that is known
+		held := e.Cardinality(rel) == Single && i <= 1
+		var first []byte
+		if held {
+			first, err = e.appendItems(nil, rel, opts)
+		} else {
+			dst, err = e.appendItems(append(dst, '['), rel, opts)
+		}
+		if err == nil {
+			dst, err = s.flush(dst)
+		}
+		if err != nil {
+			return nil, err
+		}
+		relPath := pointer(pointer("", EMBEDDED), rel)
+		// A producer that ignores the error returned by yield gets it again
+		var failed error
+		err = produce(func(item any) error {
+			if failed != nil {
+				return failed
+			}
+			if isNil(item) {
+				failed = &PathError{Path: index(relPath, i), Err: ErrNilEmbed}
+				return failed
+			}
+			if held && i == 0 {
+				first, failed = appendValue(first, item, opts)
+				if failed != nil {
+					failed = &PathError{Path: index(relPath, i), Err: failed}
+					return failed
+				}
+				i++
+				return nil
+			}
+			if held {
+				// A second item makes the relation an array
+				dst = append(append(dst, '['), first...)
+				held = false
+			}
+			// dst was flushed, the separator cannot be told from it
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			appended, err := appendValue(dst, item, opts)
+			if err != nil {
+				failed = &PathError{Path: index(relPath, i), Err: err}
+				return failed
+			}
+			i++
+			dst, failed = s.flush(appended)
+			return failed
+		})
+		if failed != nil {
+			return nil, failed
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.counts[rel] = i
+		switch {
+		case !held:
+			dst = append(dst, ']')
+		case i == 1:
+			dst = append(dst, first...)
+		default:
+			dst = append(dst, "[]"...)
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// flush writes dst and returns it emptied
+func (s *embedStream) flush(dst []byte) ([]byte, error) {
+	if len(dst) == 0 {
+		return dst, nil
+	}
+	_, err := s.w.Write(dst)
+	if err != nil {
+		return nil, err
+	}
+	return dst[:0], nil
+}
+
+// streamedCount is state holding the number of items of a streamed
+// relation. It is encoded after the embedded resources, once the count is
+// known.
+type streamedCount struct {
+	stream *embedStream
+	rel    string
+}
+
+// appendHAL appends the number of items written for the relation
+func (c streamedCount) appendHAL(dst []byte, opts *encodeOptions) ([]byte, error) {
+	return strconv.AppendInt(dst, int64(c.stream.counts[c.rel]), 10), nil
+}
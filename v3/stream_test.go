@@ -0,0 +1,277 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streamOrder returns an embedded order for the streaming tests
+func streamOrder(i int) *Resource[any] {
+	order := NewResource[any]()
+	order.Self(fmt.Sprintf("/orders/%d", i))
+	order.Set("total", float64(i)+0.5)
+	order.Set("currency", "USD")
+	return order
+}
+
+// streamOrders produces count orders
+func streamOrders(count int) EmbedProducer {
+	return func(yield func(item any) error) error {
+		for i := 0; i < count; i++ {
+			err := yield(streamOrder(i))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// writeRecorder records every write made by the encoder
+type writeRecorder struct {
+	bytes.Buffer
+	writes []string
+	// failAt makes the write of that index fail, when positive
+	failAt int
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	if w.failAt > 0 && len(w.writes) == w.failAt {
+		return 0, errors.New("disk full")
+	}
+	w.writes = append(w.writes, string(p))
+	return w.Buffer.Write(p)
+}
+
+func TestEncodeStreamMatchesMarshal(t *testing.T) {
+	newResource := func() *Resource[any] {
+		r := NewResource[any]()
+		r.Self("/orders")
+		r.AddCurie(&Curie{Name: "ea", Href: "/docs/{rel}", Templated: true})
+		r.AddLink("next", &Link{Href: "/orders?page=2"})
+		r.AddEmbed("ea:basket", NewResource[any]())
+		r.Set("currentlyProcessing", 14)
+		r.Set("shippedToday", 20)
+		return r
+	}
+	r := newResource()
+
+	var streamed writeRecorder
+	err := NewEncoder(&streamed).EncodeStream(r, map[string]EmbedProducer{
+		"ea:order": streamOrders(3),
+		"empty":    streamOrders(0),
+	})
+	assert.NoError(t, err)
+
+	// The same resource holding every item
+	expected := newResource()
+	for i := 0; i < 3; i++ {
+		expected.AddEmbed("ea:order", streamOrder(i))
+	}
+	expected.Embeds.add("empty")
+	b, err := json.Marshal(expected)
+	assert.NoError(t, err)
+	assert.Equal(t, string(b)+"\n", streamed.String())
+
+	// Links are written before the first item, then each item on its own
+	assert.Len(t, streamed.writes, 6)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders"},"curies":[{"name":"ea","href":"/docs/{rel}","templated":true}],"next":[{"href":"/orders?page=2"}]},"_embedded":{"ea:basket":[{}],"ea:order":[`, streamed.writes[0])
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders/0"}},"currency":"USD","total":0.5}`, streamed.writes[1])
+	assert.Equal(t, `,{"_links":{"self":{"href":"/orders/1"}},"currency":"USD","total":1.5}`, streamed.writes[2])
+	assert.Equal(t, `],"empty":[`, streamed.writes[4])
+	assert.Equal(t, `]},"currentlyProcessing":14,"shippedToday":20}`+"\n", streamed.writes[5])
+}
+
+func TestEncodeStreamSections(t *testing.T) {
+	// encodeBoth returns what EncodeStream writes for the resource built by
+	// build, and what Encode writes once items are embedded into it by embed
+	encodeBoth := func(build func() *Resource[any], producers map[string]EmbedProducer, embed func(r *Resource[any])) (string, string) {
+		var streamed bytes.Buffer
+		assert.NoError(t, NewEncoder(&streamed).EncodeStream(build(), producers))
+		expected := build()
+		embed(expected)
+		var encoded bytes.Buffer
+		assert.NoError(t, NewEncoder(&encoded).Encode(expected))
+		return encoded.String(), streamed.String()
+	}
+	plain := func() *Resource[any] {
+		r := NewResource[any]()
+		r.Self("/orders")
+		return r
+	}
+	none := func(r *Resource[any]) {}
+
+	// Nothing embedded and nothing to stream writes no _embedded section
+	expected, streamed := encodeBoth(plain, nil, none)
+	assert.Equal(t, expected, streamed)
+	assert.NotContains(t, streamed, EMBEDDED)
+	expected, streamed = encodeBoth(plain, map[string]EmbedProducer{}, none)
+	assert.Equal(t, expected, streamed)
+
+	// A single relation stays an object while it holds one item
+	single := func() *Resource[any] {
+		r := plain()
+		r.Embeds.add("latest")
+		r.Embeds.SetCardinality("latest", Single)
+		return r
+	}
+	expected, streamed = encodeBoth(single, map[string]EmbedProducer{"latest": streamOrders(1)}, func(r *Resource[any]) {
+		r.SetEmbed("latest", streamOrder(0))
+	})
+	assert.Equal(t, expected, streamed)
+	assert.Contains(t, streamed, `"latest":{`)
+
+	expected, streamed = encodeBoth(single, map[string]EmbedProducer{"latest": streamOrders(0)}, none)
+	assert.Equal(t, expected, streamed)
+
+	existing := func() *Resource[any] {
+		r := plain()
+		r.SetEmbed("latest", streamOrder(0))
+		return r
+	}
+	expected, streamed = encodeBoth(existing, map[string]EmbedProducer{"latest": streamOrders(0)}, none)
+	assert.Equal(t, expected, streamed)
+	assert.Contains(t, streamed, `"latest":{`)
+
+	// and becomes an array once it holds more
+	expected, streamed = encodeBoth(existing, map[string]EmbedProducer{"latest": streamOrders(2)}, func(r *Resource[any]) {
+		r.AddEmbed("latest", streamOrder(0))
+		r.AddEmbed("latest", streamOrder(1))
+	})
+	assert.Equal(t, expected, streamed)
+	expected, streamed = encodeBoth(single, map[string]EmbedProducer{"latest": streamOrders(3)}, func(r *Resource[any]) {
+		for i := 0; i < 3; i++ {
+			r.AddEmbed("latest", streamOrder(i))
+		}
+	})
+	assert.Equal(t, expected, streamed)
+	assert.Contains(t, streamed, `"latest":[`)
+}
+
+func TestEncodeStreamAppendsToEmbeds(t *testing.T) {
+	r := NewResource[any]()
+	r.AddEmbed("item", streamOrder(0))
+
+	items := make(chan *Resource[any], 2)
+	items <- streamOrder(1)
+	items <- streamOrder(2)
+	close(items)
+
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).SetKeyOrder(InsertionOrder).EncodeStream(r, map[string]EmbedProducer{"item": EmbedChannel(items)})
+	assert.NoError(t, err)
+
+	expected := NewResource[any]()
+	for i := 0; i < 3; i++ {
+		expected.AddEmbed("item", streamOrder(i))
+	}
+	var expectedBuffer bytes.Buffer
+	assert.NoError(t, NewEncoder(&expectedBuffer).SetKeyOrder(InsertionOrder).Encode(expected))
+	assert.Equal(t, expectedBuffer.String(), buffer.String())
+
+	// The resource itself is left unchanged
+	assert.Equal(t, 1, r.Embeds.count("item"))
+}
+
+func TestEncodeStreamCollection(t *testing.T) {
+	type order struct {
+		Self  *Link `hal:"self"`
+		Total int   `json:"total"`
+	}
+	collection := NewCollection("order", []order{{Self: &Link{Href: "/orders/1"}, Total: 10}})
+	total := 2
+	collection.Total = &total
+
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).EncodeStream(collection, map[string]EmbedProducer{
+		"order": func(yield func(item any) error) error {
+			return yield(order{Self: &Link{Href: "/orders/2"}, Total: 20})
+		},
+	})
+	assert.NoError(t, err)
+
+	// Streamed items are counted
+	collection.Items = append(collection.Items, order{Self: &Link{Href: "/orders/2"}, Total: 20})
+	b, err := json.Marshal(collection)
+	assert.NoError(t, err)
+	assert.Equal(t, string(b)+"\n", buffer.String())
+}
+
+func TestEncodeStreamErrors(t *testing.T) {
+	r := NewResource[any]()
+	r.Self("/orders")
+	r.Set("count", 2)
+
+	// A producer error stops the stream after what was already written
+	var buffer bytes.Buffer
+	failure := errors.New("database gone")
+	err := NewEncoder(&buffer).EncodeStream(r, map[string]EmbedProducer{
+		"order": func(yield func(item any) error) error {
+			err := yield(streamOrder(0))
+			if err != nil {
+				return err
+			}
+			return failure
+		},
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders"}},"_embedded":{"order":[{"_links":{"self":{"href":"/orders/0"}},"currency":"USD","total":0.5}`, buffer.String())
+
+	// Items that cannot be encoded are reported with their path, even when
+	// the producer ignores the error
+	buffer.Reset()
+	err = NewEncoder(&buffer).EncodeStream(r, map[string]EmbedProducer{
+		"order": func(yield func(item any) error) error {
+			_ = yield(streamOrder(0))
+			_ = yield(make(chan int))
+			_ = yield(streamOrder(2))
+			return nil
+		},
+	})
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+	assert.Equal(t, "/_embedded/order/1", pathErr.Path)
+	assert.NotContains(t, buffer.String(), "/orders/2")
+
+	err = NewEncoder(&buffer).EncodeStream(r, map[string]EmbedProducer{
+		"order": func(yield func(item any) error) error {
+			return yield(nil)
+		},
+	})
+	assert.ErrorIs(t, err, ErrNilEmbed)
+
+	// Writer errors are returned to the producer and by EncodeStream
+	writer := &writeRecorder{failAt: 2}
+	produced := 0
+	err = NewEncoder(writer).EncodeStream(r, map[string]EmbedProducer{
+		"order": func(yield func(item any) error) error {
+			for {
+				err := yield(streamOrder(produced))
+				if err != nil {
+					return err
+				}
+				produced++
+			}
+		},
+	})
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, 1, produced)
+
+	// Streams need a resource and cannot be indented
+	err = NewEncoder(&buffer).EncodeStream(map[string]int{"a": 1}, nil)
+	assert.ErrorIs(t, err, ErrNotResource)
+	err = NewEncoder(&buffer).SetIndent("", "  ").EncodeStream(r, nil)
+	assert.ErrorIs(t, err, ErrStreamIndent)
+	err = NewEncoder(&buffer).EncodeStream(r, map[string]EmbedProducer{"\xff": streamOrders(1)})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	// _embedded state cannot be merged into a stream
+	r.Set(EMBEDDED, map[string]any{})
+	err = NewEncoder(&buffer).SetReservedKeys(MergeReservedKeys).EncodeStream(r, nil)
+	assert.ErrorIs(t, err, ErrReservedKey)
+}
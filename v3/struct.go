@@ -0,0 +1,836 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Struct tag kinds understood by Marshal, set with the `hal` struct tag
+const (
+	// TagSelf marks a string, Link or *Link field as the self link
+	TagSelf = "self"
+	// TagLink marks a field as links of a relation, `hal:"link,rel=ea:basket"`
+	TagLink = "link"
+	// TagEmbed marks a field as embedded resources of a relation, `hal:"embed,rel=ea:order"`
+	TagEmbed = "embed"
+	// TagCuries marks a []Curie field as the curies
+	TagCuries = "curies"
+	// TagLinks marks a Links or *Links field holding additional links
+	TagLinks = "links"
+	// TagEmbeds marks an Embeds or *Embeds field holding additional embedded resources
+	TagEmbeds = "embeds"
+)
+
+// halField describes a struct field carrying a `hal` tag
+type halField struct {
+	index []int
+	kind  string
+	rel   string
+}
+
+// structInfo caches how a struct type maps onto a HAL document
+type structInfo struct {
+	fields []halField
+	// halKeys are the keys encoding/json reads for the hal tagged fields
+	halKeys []string
+	// selfUnmarshaling records structs that implement the encoding/json
+	// unmarshaling interfaces, whose methods may call Unmarshal. Their
+	// state is decoded through a method free copy built from state rather
+	// than through the struct itself.
+	selfUnmarshaling bool
+	// state holds the fields written as state, which are marshaled through
+	// a method free copy
+	state []stateField
+}
+
+// stateField is a state field of a struct as encoding/json sees it, with
+// the fields of embedded structs promoted
+type stateField struct {
+	index []int
+	name  string
+	// tag is the json tag of the field in the method free copy
+	tag    string
+	typ    reflect.Type
+	tagged bool
+	depth  int
+}
+
+var structInfoCache sync.Map // map[reflect.Type]*structInfo
+
+var (
+	marshalerType       = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+var (
+	linkType   = reflect.TypeOf(Link{})
+	linksType  = reflect.TypeOf(Links{})
+	embedsType = reflect.TypeOf(Embeds{})
+	curieType  = reflect.TypeOf(Curie{})
+)
+
+// Marshal returns the HAL encoding of v. Structs are marshaled using their
+// `hal` struct tags: tagged fields become links and embedded resources and
+// all other fields become state following the rules of encoding/json.
+// Resources, Links and Embeds marshal as they do with json.Marshal.
+func Marshal(v any) ([]byte, error) {
+	var b []byte
+	var err error
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Struct {
+		// Address the value so that pointer receiver marshalers are found
+		addressable := reflect.New(rv.Type())
+		addressable.Elem().Set(rv)
+		v = addressable.Interface()
+	}
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if _, ok := v.(halEncoder); ok || rv.Kind() != reflect.Struct {
+		b, err = marshalValue(v, defaultEncodeOptions)
+	} else {
+		var r *Resource[any]
+		r, err = resourceFromStruct(rv, nil)
+		if err == nil {
+			b, err = r.appendHAL(nil, defaultEncodeOptions)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// isHALStruct reports whether rv, after dereferencing, is a struct with `hal` tags
+func isHALStruct(rv reflect.Value) (reflect.Value, bool) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return rv, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return rv, false
+	}
+	// Tag errors are surfaced by resourceFromStruct
+	info, err := structInfoFor(rv.Type())
+	return rv, err != nil || len(info.fields) > 0
+}
+
+// structInfoFor returns the cached structInfo for t
+func structInfoFor(t reflect.Type) (*structInfo, error) {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo), nil
+	}
+	info := &structInfo{
+		selfUnmarshaling: implements(t, unmarshalerType, textUnmarshalerType),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("hal")
+		// Unexported fields cannot be read or set, they are ignored as
+		// encoding/json ignores them
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		hf, err := parseHALTag(field, tag)
+		if err != nil {
+			return nil, err
+		}
+		info.fields = append(info.fields, hf)
+		if field.Anonymous {
+			// The keys of an embedded section cannot be told apart from state
+			info.selfUnmarshaling = true
+		} else if name := field.Tag.Get("json"); name != "-" {
+			info.halKeys = append(info.halKeys, jsonName(field))
+		}
+	}
+	var state []stateField
+	collectStateFields(t, nil, 0, map[reflect.Type]bool{t: true}, &state)
+	info.state = dominantFields(state)
+	actual, _ := structInfoCache.LoadOrStore(t, info)
+	return actual.(*structInfo), nil
+}
+
+// implements reports whether t or *t implements one of the interfaces
+func implements(t reflect.Type, interfaces ...reflect.Type) bool {
+	for _, i := range interfaces {
+		if t.Implements(i) || reflect.PointerTo(t).Implements(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectStateFields appends the state fields of the struct type t, found
+// at index, to fields. Untagged embedded structs have their fields promoted
+// as encoding/json does, unless they marshal themselves: those are kept as
+// a field named after their type, as their methods would not be promoted
+// by the method free copy. Hal tagged fields of the outermost struct are
+// left out.
+func collectStateFields(t reflect.Type, index []int, depth int, visited map[reflect.Type]bool, fields *[]stateField) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tag, ok := field.Tag.Lookup("hal"); depth == 0 && ok && tag != "-" {
+			continue
+		}
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(jsonTag, ",")
+		ft := field.Type
+		if field.Anonymous && ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		fieldIndex := append(index[:len(index):len(index)], i)
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct &&
+			!implements(field.Type, marshalerType, textMarshalerType, unmarshalerType, textUnmarshalerType) {
+			if !visited[ft] {
+				visited[ft] = true
+				collectStateFields(ft, fieldIndex, depth+1, visited, fields)
+				delete(visited, ft)
+			}
+			continue
+		}
+		// Unexported fields are ignored by encoding/json as well
+		if !field.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		tag := name
+		if options != "" {
+			tag += "," + options
+		}
+		*fields = append(*fields, stateField{
+			index:  fieldIndex,
+			name:   name,
+			tag:    tag,
+			typ:    field.Type,
+			tagged: tagged,
+			depth:  depth,
+		})
+	}
+}
+
+// dominantFields applies the rules encoding/json uses for fields sharing a
+// name: the shallowest field wins, then the only tagged one. Names that
+// remain ambiguous are dropped.
+func dominantFields(fields []stateField) []stateField {
+	byName := make(map[string][]stateField)
+	for _, field := range fields {
+		byName[field.name] = append(byName[field.name], field)
+	}
+	var dominant []stateField
+	for _, field := range fields {
+		candidates := byName[field.name]
+		var shallowest []stateField
+		for _, c := range candidates {
+			switch {
+			case len(shallowest) == 0 || c.depth < shallowest[0].depth:
+				shallowest = []stateField{c}
+			case c.depth == shallowest[0].depth:
+				shallowest = append(shallowest, c)
+			}
+		}
+		if len(shallowest) > 1 {
+			var tagged []stateField
+			for _, c := range shallowest {
+				if c.tagged {
+					tagged = append(tagged, c)
+				}
+			}
+			shallowest = tagged
+		}
+		if len(shallowest) == 1 && reflect.DeepEqual(shallowest[0].index, field.index) {
+			dominant = append(dominant, field)
+		}
+	}
+	return dominant
+}
+
+// stateCopy returns a method free copy of the state fields of rv and the
+// fields of rv it was copied from. Fields behind a nil embedded pointer are
+// left out, or allocated when allocate is set and the pointer can be set.
+func (info *structInfo) stateCopy(rv reflect.Value, allocate bool) (reflect.Value, []reflect.Value) {
+	var fields []reflect.StructField
+	var values []reflect.Value
+	for _, sf := range info.state {
+		value, ok := fieldByIndex(rv, sf.index, allocate)
+		if !ok {
+			continue
+		}
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("F%d", len(fields)),
+			Type: sf.typ,
+			Tag:  reflect.StructTag(`json:` + strconv.Quote(sf.tag)),
+		})
+		values = append(values, value)
+	}
+	state := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, value := range values {
+		state.Field(i).Set(value)
+	}
+	return state, values
+}
+
+// fieldByIndex returns the field of rv at index, following embedded pointers
+func fieldByIndex(rv reflect.Value, index []int, allocate bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !allocate || !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// parseHALTag parses the `hal` tag of field
+func parseHALTag(field reflect.StructField, tag string) (halField, error) {
+	parts := strings.Split(tag, ",")
+	hf := halField{index: field.Index, kind: parts[0]}
+	for _, option := range parts[1:] {
+		if rel, ok := strings.CutPrefix(option, "rel="); ok {
+			hf.rel = rel
+		}
+	}
+	switch hf.kind {
+	case TagSelf, TagCuries, TagLinks, TagEmbeds:
+	case TagLink, TagEmbed:
+		if hf.rel == "" {
+			hf.rel = jsonName(field)
+		}
+	default:
+		return hf, fmt.Errorf("%w: unknown kind %q on field %s", ErrInvalidTag, hf.kind, field.Name)
+	}
+	return hf, nil
+}
+
+// jsonName returns the name encoding/json would use for field
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// resourceFromStruct converts a struct with `hal` tags into a Resource
+// whose curies are inherited from parent
+func resourceFromStruct(rv reflect.Value, parent *Links) (*Resource[any], error) {
+	info, err := structInfoFor(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	r := NewResource[any]()
+
+	// The state is the struct as encoding/json marshals it, without the hal
+	// tagged fields. It goes through a method free copy holding only the
+	// state fields, so that the hal tagged fields are not marshaled and a
+	// MarshalJSON of the struct may call Marshal without recursing.
+	state, _ := info.stateCopy(rv, false)
+	b, err := json.Marshal(state.Interface())
+	if err != nil {
+		return nil, err
+	}
+	members, err := splitObject(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		r.Set(m.key, m.value)
+	}
+
+	// Curies and whole sections first, links may depend on curies
+	for _, hf := range info.fields {
+		field := rv.FieldByIndex(hf.index)
+		switch hf.kind {
+		case TagLinks:
+			if links, ok := derefAs[Links](field, linksType); ok {
+				r.Links = links.clone()
+			}
+		case TagEmbeds:
+			if embeds, ok := derefAs[Embeds](field, embedsType); ok {
+				r.Embeds = embeds.clone()
+			}
+		}
+	}
+	r.Links.parent = parent
+	for _, hf := range info.fields {
+		field := rv.FieldByIndex(hf.index)
+		if hf.kind != TagCuries {
+			continue
+		}
+		curies, ok := field.Interface().([]Curie)
+		if !ok {
+			return nil, fmt.Errorf("%w: curies field must be []Curie", ErrInvalidTag)
+		}
+		for i := range curies {
+			r.AddCurie(&curies[i])
+		}
+	}
+	for _, hf := range info.fields {
+		field := rv.FieldByIndex(hf.index)
+		switch hf.kind {
+		case TagSelf:
+			links, err := linksFromField(field)
+			if err != nil {
+				return nil, err
+			}
+			if len(links) > 0 {
+				r.Links.Self = links[0]
+			}
+		case TagLink:
+			links, err := linksFromField(field)
+			if err != nil {
+				return nil, err
+			}
+			cardinality := Many
+			if field.Kind() != reflect.Slice {
+				cardinality = Single
+			}
+			// Structs are converted once embedded, their curies must be in scope
+			if prefix, ok := curiePrefix(hf.rel); ok && len(links) > 0 && !r.Links.hasCurie(prefix) {
+				return nil, ErrNoCurie
+			}
+			for _, link := range links {
+				err = r.AddLink(hf.rel, link, cardinality)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case TagEmbed:
+			err = embedField(r, hf.rel, field)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// derefAs returns the T held by field, which is either a T or a *T
+func derefAs[T any](field reflect.Value, t reflect.Type) (*T, bool) {
+	switch {
+	case field.Type() == t:
+		value := field.Interface().(T)
+		return &value, true
+	case field.Type() == reflect.PointerTo(t) && !field.IsNil():
+		return field.Interface().(*T), true
+	}
+	return nil, false
+}
+
+// linksFromField returns the links held by a string, Link or *Link field,
+// or a slice of them. Empty values are skipped.
+func linksFromField(field reflect.Value) ([]*Link, error) {
+	if field.Kind() == reflect.Slice {
+		var links []*Link
+		for i := 0; i < field.Len(); i++ {
+			link, err := linkFromValue(field.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if link != nil {
+				links = append(links, link)
+			}
+		}
+		return links, nil
+	}
+	link, err := linkFromValue(field)
+	if err != nil || link == nil {
+		return nil, err
+	}
+	return []*Link{link}, nil
+}
+
+// linkFromValue converts a string, Link or *Link to a *Link
+func linkFromValue(v reflect.Value) (*Link, error) {
+	switch {
+	case v.Kind() == reflect.String:
+		if v.String() == "" {
+			return nil, nil
+		}
+		return &Link{Href: v.String()}, nil
+	case v.Type() == linkType:
+		link := v.Interface().(Link)
+		return &link, nil
+	case v.Type() == reflect.PointerTo(linkType):
+		return v.Interface().(*Link), nil
+	}
+	return nil, fmt.Errorf("%w: cannot use %s as a link", ErrInvalidTag, v.Type())
+}
+
+// embedField adds the resources held by field to r under rel. Slices and
+// arrays embed as many, other values as a single resource. Nil values are skipped.
+func embedField(r *Resource[any], rel string, field reflect.Value) error {
+	if field.Kind() == reflect.Slice || field.Kind() == reflect.Array {
+		if field.Kind() == reflect.Slice && field.IsNil() {
+			return nil
+		}
+		r.Embeds.add(rel)
+		for i := 0; i < field.Len(); i++ {
+			embed, err := embedValue(r, field.Index(i))
+			if err != nil {
+				return err
+			}
+			err = r.Embeds.AddEmbed(rel, embed)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if (field.Kind() == reflect.Pointer || field.Kind() == reflect.Interface) && field.IsNil() {
+		return nil
+	}
+	embed, err := embedValue(r, field)
+	if err != nil {
+		return err
+	}
+	return r.Embeds.SetEmbed(rel, embed)
+}
+
+// embedValue returns the value to embed in r for v. Structs with `hal` tags
+// are converted now so that their links may use the curies of r.
+func embedValue(r *Resource[any], v reflect.Value) (any, error) {
+	if _, ok := v.Interface().(halEncoder); !ok {
+		if rv, ok := isHALStruct(v); ok {
+			return resourceFromStruct(rv, r.Links)
+		}
+	}
+	return v.Interface(), nil
+}
+
+// rawMember is a member of a JSON object in document order
+type rawMember struct {
+	key   string
+	value json.RawMessage
+}
+
+// splitObject splits an encoded JSON object into its members, in order. A
+// null or absent object has no members.
+func splitObject(b []byte) ([]rawMember, error) {
+	if b == nil {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected object")
+	}
+	var members []rawMember
+	for dec.More() {
+		token, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		err = dec.Decode(&value)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, rawMember{key: token.(string), value: value})
+	}
+	return members, nil
+}
+
+// Unmarshal parses the HAL document data into the value pointed to by v.
+// Structs are filled using their `hal` struct tags: tagged fields receive
+// the links and embedded resources of their relation, recursively decoding
+// embedded resources into the field's type, and all other fields receive
+// the state following the rules of encoding/json.
+func Unmarshal(data []byte, v any) error {
+	return unmarshalValue(data, v, newDecodeOptions(), "")
+}
+
+// unmarshalValue decodes data found at path into v, passing opts down to
+// HAL types and structs
+func unmarshalValue(data []byte, v any, opts *decodeOptions, path string) error {
+	if h, ok := v.(halDecoder); ok {
+		return h.unmarshalHAL(data, opts, path)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	if rv.Elem().Kind() != reflect.Struct {
+		return json.Unmarshal(data, v)
+	}
+	return unmarshalStruct(data, rv.Elem(), opts, path)
+}
+
+// unmarshalStruct decodes data found at path into the struct rv
+func unmarshalStruct(data []byte, rv reflect.Value, opts *decodeOptions, path string) error {
+	info, err := structInfoFor(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	linksData, embeddedData, state, err := splitDocument(data)
+	if err != nil {
+		return err
+	}
+	links := NewLinks()
+	links.parent = opts.parent
+	if linksData != nil {
+		err = links.unmarshalHAL(linksData, opts, pointer(path, LINKS))
+		if err != nil {
+			return err
+		}
+	}
+	embedded := map[string]json.RawMessage{}
+	rels, err := splitObject(embeddedData)
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		embedded[rel.key] = rel.value
+	}
+
+	// Decode the state into the struct, existing values are kept for
+	// properties that are not present, as encoding/json does. Structs that
+	// unmarshal themselves go through a method free copy, so that their
+	// UnmarshalJSON may call Unmarshal without recursing.
+	if info.selfUnmarshaling {
+		stateValue, values := info.stateCopy(rv, true)
+		err = json.Unmarshal(state, stateValue.Addr().Interface())
+		if err != nil {
+			return err
+		}
+		for i, value := range values {
+			value.Set(stateValue.Field(i))
+		}
+	} else {
+		state, err = stripKeys(state, info.halKeys)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(state, rv.Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, hf := range info.fields {
+		field := rv.FieldByIndex(hf.index)
+		switch hf.kind {
+		case TagSelf:
+			if links.Self != nil {
+				err = setLinks(field, []*Link{links.Self})
+			}
+		case TagLink:
+			if rel, ok := links.Relations[hf.rel]; ok {
+				err = setLinks(field, rel)
+			}
+		case TagCuries:
+			err = setField(field, reflect.ValueOf(links.Curies))
+		case TagLinks:
+			err = setSection(field, links, linksType)
+		case TagEmbeds:
+			embeds := NewEmbeds()
+			if embeddedData != nil {
+				err = opts.within(links, func() error {
+					return embeds.unmarshalHAL(embeddedData, opts, pointer(path, EMBEDDED))
+				})
+			}
+			if err == nil {
+				err = setSection(field, embeds, embedsType)
+			}
+		case TagEmbed:
+			if raw, ok := embedded[hf.rel]; ok {
+				err = opts.within(links, func() error {
+					return setEmbed(field, raw, opts, pointer(pointer(path, EMBEDDED), hf.rel))
+				})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setField assigns value to field when their types match
+func setField(field reflect.Value, value reflect.Value) error {
+	if !value.Type().AssignableTo(field.Type()) {
+		return fmt.Errorf("%w: cannot assign %s to %s", ErrInvalidTag, value.Type(), field.Type())
+	}
+	field.Set(value)
+	return nil
+}
+
+// setSection assigns section, a *Links or *Embeds, to a field of type T or *T
+func setSection(field reflect.Value, section any, t reflect.Type) error {
+	value := reflect.ValueOf(section)
+	if field.Type() == t {
+		value = value.Elem()
+	}
+	return setField(field, value)
+}
+
+// setLinks assigns links to a string, Link or *Link field, or a slice of
+// them. Fields that are not slices receive the first link.
+func setLinks(field reflect.Value, links []*Link) error {
+	if field.Kind() != reflect.Slice {
+		if len(links) == 0 {
+			return nil
+		}
+		return setLink(field, links[0])
+	}
+	slice := reflect.MakeSlice(field.Type(), len(links), len(links))
+	for i, link := range links {
+		err := setLink(slice.Index(i), link)
+		if err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// setLink assigns link to a string, Link or *Link value
+func setLink(v reflect.Value, link *Link) error {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(link.Href)
+	case v.Type() == linkType:
+		v.Set(reflect.ValueOf(*link))
+	case v.Type() == reflect.PointerTo(linkType):
+		v.Set(reflect.ValueOf(link))
+	default:
+		return fmt.Errorf("%w: cannot use %s as a link", ErrInvalidTag, v.Type())
+	}
+	return nil
+}
+
+// setEmbed decodes an embedded relation found at path into field. Slices
+// receive every resource, other fields the first one.
+func setEmbed(field reflect.Value, raw json.RawMessage, opts *decodeOptions, path string) error {
+	var items []json.RawMessage
+	isArray := len(bytes.TrimSpace(raw)) > 0 && bytes.TrimSpace(raw)[0] == '['
+	if isArray {
+		err := json.Unmarshal(raw, &items)
+		if err != nil {
+			return err
+		}
+	} else {
+		items = []json.RawMessage{raw}
+	}
+	if field.Kind() != reflect.Slice {
+		if len(items) == 0 {
+			return nil
+		}
+		if isArray {
+			path = index(path, 0)
+		}
+		return decodeInto(items[0], field, opts, path)
+	}
+	slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		itemPath := path
+		if isArray {
+			itemPath = index(path, i)
+		}
+		err := decodeInto(item, slice.Index(i), opts, itemPath)
+		if err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// decodeInto decodes data found at path into the addressable value v,
+// allocating pointers as needed
+func decodeInto(data []byte, v reflect.Value, opts *decodeOptions, path string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(data, v.Interface(), opts, path)
+	}
+	return unmarshalValue(data, v.Addr().Interface(), opts, path)
+}
+
+// stripKeys removes the members of the encoded JSON object state that
+// encoding/json would decode into a field named by keys, which it matches
+// case insensitively
+func stripKeys(state []byte, keys []string) ([]byte, error) {
+	if len(keys) == 0 {
+		return state, nil
+	}
+	members, err := splitObject(state)
+	if err != nil {
+		return nil, err
+	}
+	stripped := []byte{'{'}
+	for _, m := range members {
+		if slices.ContainsFunc(keys, func(key string) bool { return strings.EqualFold(key, m.key) }) {
+			continue
+		}
+		stripped, err = appendKey(stripped, m.key)
+		if err != nil {
+			return nil, err
+		}
+		stripped = append(stripped, m.value...)
+	}
+	return append(stripped, '}'), nil
+}
+
+// splitDocument splits an encoded HAL document into its _links and
+// _embedded sections, which are nil when absent, and an object holding the
+// remaining state properties
+func splitDocument(data []byte) (links json.RawMessage, embedded json.RawMessage, state []byte, err error) {
+	members, err := splitObject(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for _, m := range members {
+		switch m.key {
+		case LINKS:
+			links = m.value
+		case EMBEDDED:
+			embedded = m.value
+		default:
+			if buffer.Len() > 1 {
+				buffer.WriteByte(',')
+			}
+			key, err := quoteKey(m.key)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			buffer.WriteString(key)
+			buffer.WriteByte(':')
+			buffer.Write(m.value)
+		}
+	}
+	buffer.WriteByte('}')
+	return links, embedded, buffer.Bytes(), nil
+}
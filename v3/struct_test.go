@@ -0,0 +1,452 @@
+package haljson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testCustomer struct {
+	Self string `hal:"self"`
+	Name string `json:"name"`
+}
+
+type testOrder struct {
+	Self     string        `hal:"self"`
+	Curies   []Curie       `hal:"curies"`
+	Basket   *Link         `hal:"link,rel=ea:basket"`
+	Items    []string      `hal:"link,rel=ea:item"`
+	Customer *testCustomer `hal:"embed,rel=ea:customer"`
+	Total    float64       `json:"total"`
+	Currency string        `json:"currency"`
+	Status   string        `json:"status,omitempty"`
+	Note     string        `json:"-"`
+	internal string
+}
+
+func TestMarshalStruct(t *testing.T) {
+	order := testOrder{
+		Self:     "/orders/123",
+		Curies:   []Curie{{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true}},
+		Basket:   &Link{Href: "/baskets/98712"},
+		Items:    []string{"/items/1", "/items/2"},
+		Customer: &testCustomer{Self: "/customers/7809", Name: "Jane"},
+		Total:    30,
+		Currency: "USD",
+		Note:     "hidden",
+		internal: "hidden",
+	}
+
+	b, err := Marshal(order)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders/123"},"curies":[{"name":"ea","href":"http://example.com/docs/rels/{rel}","templated":true}],`+
+		`"ea:basket":{"href":"/baskets/98712"},"ea:item":[{"href":"/items/1"},{"href":"/items/2"}]},`+
+		`"_embedded":{"ea:customer":{"_links":{"self":{"href":"/customers/7809"}},"name":"Jane"}},`+
+		`"currency":"USD","total":30}`, string(b))
+
+	// Pointers marshal the same way
+	b2, err := Marshal(&order)
+	assert.Nil(t, err)
+	assert.Equal(t, string(b), string(b2))
+}
+
+func TestMarshalStructInsertionOrder(t *testing.T) {
+	order := testOrder{Self: "/orders/1", Total: 10, Currency: "EUR", Status: "shipped"}
+
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).SetKeyOrder(InsertionOrder).Encode(order)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders/1"}},"total":10,"currency":"EUR","status":"shipped"}`+"\n", buffer.String())
+}
+
+func TestMarshalStructEncodingJSONRules(t *testing.T) {
+	type Audit struct {
+		Created time.Time `json:"created"`
+	}
+	type Document struct {
+		Audit
+		Self    Link   `hal:"self"`
+		Count   int    `json:"count,string"`
+		Skipped string `json:",omitempty"`
+		Raw     json.RawMessage
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	doc := Document{Audit: Audit{Created: created}, Self: Link{Href: "/docs/1", Title: "Doc"}, Count: 3, Raw: json.RawMessage(`[1,2]`)}
+
+	b, err := Marshal(doc)
+	assert.Nil(t, err)
+
+	var decoded map[string]any
+	assert.Nil(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, "2024-01-02T03:04:05Z", decoded["created"])
+	assert.Equal(t, "3", decoded["count"])
+	assert.Equal(t, []any{float64(1), float64(2)}, decoded["Raw"])
+	assert.NotContains(t, decoded, "Skipped")
+	assert.NotContains(t, decoded, "Self")
+	assert.Equal(t, map[string]any{"self": map[string]any{"href": "/docs/1", "title": "Doc"}}, decoded["_links"])
+}
+
+// base is an unexported type whose exported fields are promoted
+type base struct {
+	ID int `json:"id"`
+}
+
+// stamp has methods, and marshals itself as a string
+type stamp struct {
+	time.Time
+}
+
+func TestMarshalStructEmbeddedTypes(t *testing.T) {
+	type Document struct {
+		base
+		Self string `hal:"self"`
+		Name string `json:"name"`
+	}
+	b, err := Marshal(Document{base: base{ID: 7}, Self: "/docs/7", Name: "seven"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/docs/7"}},"id":7,"name":"seven"}`, string(b))
+
+	// Types marshaling themselves are kept under their type name, as their
+	// methods would make the whole state a string
+	type Event struct {
+		time.Time
+		Self string `hal:"self"`
+	}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b, err = Marshal(Event{Time: created, Self: "/events/1"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/events/1"}},"Time":"2024-01-02T03:04:05Z"}`, string(b))
+
+	// Shallower fields win over promoted ones, nil embedded pointers are skipped
+	type Shadowed struct {
+		*stamp
+		base
+		Self string `hal:"self"`
+		ID   string `json:"id"`
+	}
+	b, err = Marshal(Shadowed{base: base{ID: 7}, Self: "/shadowed/1", ID: "outer"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/shadowed/1"}},"id":"outer"}`, string(b))
+}
+
+func TestMarshalStructUnexportedHALFields(t *testing.T) {
+	type Order struct {
+		self   string `hal:"self"`
+		basket *Link  `hal:"link,rel=basket"`
+		Total  int    `json:"total"`
+	}
+	// Unexported fields are ignored, whatever their tag
+	b, err := Marshal(Order{self: "/orders/1", basket: &Link{Href: "/baskets/1"}, Total: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"total":5}`, string(b))
+
+	var decoded Order
+	assert.Nil(t, Unmarshal([]byte(`{"_links":{"self":{"href":"/orders/1"},"basket":{"href":"/baskets/1"}},"total":5}`), &decoded))
+	assert.Equal(t, Order{Total: 5}, decoded)
+}
+
+func TestMarshalStructReservedKeys(t *testing.T) {
+	type Order struct {
+		Self     string         `hal:"self"`
+		Customer *Resource[any] `hal:"embed,rel=customer"`
+	}
+	customer := NewResource[any]()
+	customer.Set(LINKS, map[string]any{"self": map[string]any{"href": "/customers/1"}})
+
+	// The embedded resource is only encoded once, with the policy of the encoder
+	var buffer bytes.Buffer
+	err := NewEncoder(&buffer).SetReservedKeys(MergeReservedKeys).Encode(Order{Self: "/orders/1", Customer: customer})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders/1"}},"_embedded":{"customer":{"_links":{"self":{"href":"/customers/1"}}}}}`+"\n", buffer.String())
+}
+
+// selfMarshalingOrder marshals itself as HAL from its own MarshalJSON
+type selfMarshalingOrder struct {
+	Self  string `hal:"self"`
+	Total int    `json:"total"`
+}
+
+func (o selfMarshalingOrder) MarshalJSON() ([]byte, error) {
+	return Marshal(o)
+}
+
+func TestMarshalStructFromMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(selfMarshalingOrder{Self: "/orders/1", Total: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/orders/1"}},"total":5}`, string(b))
+}
+
+// selfMarshalingDocument marshals itself as HAL and promotes fields
+type selfMarshalingDocument struct {
+	base
+	time.Time
+	Self string `hal:"self"`
+}
+
+func (d selfMarshalingDocument) MarshalJSON() ([]byte, error) {
+	return Marshal(d)
+}
+
+func TestMarshalStructFromMarshalJSONEmbeddedTypes(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b, err := json.Marshal(selfMarshalingDocument{base: base{ID: 7}, Time: created, Self: "/docs/7"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"_links":{"self":{"href":"/docs/7"}},"Time":"2024-01-02T03:04:05Z","id":7}`, string(b))
+}
+
+func TestMarshalStructSections(t *testing.T) {
+	links := NewLinks()
+	links.AddLink("next", &Link{Href: "/orders?page=2"})
+	embeds := NewEmbeds()
+	embeds.add("extra", *NewResource[any]())
+
+	type Orders struct {
+		Links   *Links                   `hal:"links"`
+		Embeds  Embeds                   `hal:"embeds"`
+		Self    string                   `hal:"self"`
+		Orders  []testCustomer           `hal:"embed"`
+		Typed   *Resource[any]           `hal:"embed,rel=typed"`
+		Missing *testCustomer            `hal:"embed,rel=missing"`
+		Other   Resource[map[string]int] `hal:"embed,rel=other"`
+	}
+	other := NewResource[map[string]int]()
+	other.Data["counts"] = map[string]int{"a": 1}
+	typed := NewResource[any]()
+	typed.Self("/typed")
+
+	b, err := Marshal(Orders{
+		Links:  links,
+		Embeds: *embeds,
+		Self:   "/orders",
+		Orders: []testCustomer{{Self: "/customers/1", Name: "A"}},
+		Typed:  typed,
+		Other:  *other,
+	})
+	assert.Nil(t, err)
+
+	var decoded Resource[any]
+	assert.Nil(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, "/orders", decoded.Links.Self.Href)
+	assert.Equal(t, "/orders?page=2", decoded.Links.Relations["next"][0].Href)
+	assert.Len(t, decoded.Embeds.Relations["extra"], 1)
+	assert.Equal(t, "A", decoded.Embeds.Relations["Orders"][0].Data["name"])
+	assert.Equal(t, "/typed", decoded.Embeds.Relations["typed"][0].Links.Self.Href)
+	assert.Equal(t, map[string]any{"a": float64(1)}, decoded.Embeds.Relations["other"][0].Data["counts"])
+	assert.NotContains(t, decoded.Embeds.Relations, "missing")
+
+	// The source sections are not modified
+	assert.Nil(t, links.Self)
+	assert.Len(t, embeds.Relations, 1)
+}
+
+func TestMarshalStructErrors(t *testing.T) {
+	type NoCurie struct {
+		Basket string `hal:"link,rel=ea:basket"`
+	}
+	_, err := Marshal(NoCurie{Basket: "/baskets/1"})
+	assert.Equal(t, ErrNoCurie, err)
+
+	type BadKind struct {
+		Field string `hal:"bogus"`
+	}
+	_, err = Marshal(BadKind{})
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	type BadLink struct {
+		Field int `hal:"link,rel=next"`
+	}
+	_, err = Marshal(BadLink{Field: 1})
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	type BadCuries struct {
+		Curies []string `hal:"curies"`
+	}
+	_, err = Marshal(BadCuries{})
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	// Resources marshal as HAL whether passed by value or pointer
+	r := NewResource[int]()
+	r.Data["count"] = 1
+	b, err := Marshal(*r)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"count":1}`, string(b))
+
+	// Non struct values marshal with encoding/json
+	b, err = Marshal([]int{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, `[1,2]`, string(b))
+}
+
+const orderDocument = `{
+	"_links": {
+		"self": {"href": "/orders/123"},
+		"curies": [{"name": "ea", "href": "http://example.com/docs/rels/{rel}", "templated": true}],
+		"ea:basket": {"href": "/baskets/98712", "title": "Basket"},
+		"ea:item": [{"href": "/items/1"}, {"href": "/items/2"}]
+	},
+	"_embedded": {
+		"ea:customer": {"_links": {"self": {"href": "/customers/7809"}}, "name": "Jane"}
+	},
+	"total": 30.5,
+	"currency": "USD",
+	"status": "shipped"
+}`
+
+func TestUnmarshalStruct(t *testing.T) {
+	var order testOrder
+	err := Unmarshal([]byte(orderDocument), &order)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "/orders/123", order.Self)
+	assert.Equal(t, []Curie{{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true}}, order.Curies)
+	assert.Equal(t, &Link{Href: "/baskets/98712", Title: "Basket"}, order.Basket)
+	assert.Equal(t, []string{"/items/1", "/items/2"}, order.Items)
+	assert.Equal(t, &testCustomer{Self: "/customers/7809", Name: "Jane"}, order.Customer)
+	assert.Equal(t, 30.5, order.Total)
+	assert.Equal(t, "USD", order.Currency)
+	assert.Equal(t, "shipped", order.Status)
+
+	// Round trip through Marshal
+	b, err := Marshal(order)
+	assert.Nil(t, err)
+	var again testOrder
+	assert.Nil(t, Unmarshal(b, &again))
+	assert.Equal(t, order, again)
+}
+
+func TestUnmarshalStructTypedEmbeds(t *testing.T) {
+	type Item struct {
+		Self  Link `hal:"self"`
+		Count int  `json:"count"`
+	}
+	type Basket struct {
+		Self     *Link                  `hal:"self"`
+		Next     []*Link                `hal:"link,rel=next"`
+		Items    []Item                 `hal:"embed,rel=items"`
+		Pointers []*Item                `hal:"embed,rel=items"`
+		First    Item                   `hal:"embed,rel=items"`
+		Owner    *Resource[string]      `hal:"embed,rel=owner"`
+		Raw      map[string]any         `hal:"embed,rel=owner"`
+		Links    *Links                 `hal:"links"`
+		Embeds   Embeds                 `hal:"embeds"`
+		Absent   []Item                 `hal:"embed,rel=absent"`
+		Extra    map[string]json.Number `json:"extra"`
+	}
+	doc := `{
+		"_links": {"self": {"href": "/baskets/1"}, "next": {"href": "/baskets/2"}},
+		"_embedded": {
+			"items": [{"_links": {"self": {"href": "/items/1"}}, "count": 2}, {"count": 3}],
+			"owner": {"_links": {"self": {"href": "/people/1"}}, "name": "Sam"}
+		},
+		"extra": {"weight": 1.5}
+	}`
+
+	var basket Basket
+	err := Unmarshal([]byte(doc), &basket)
+	assert.Nil(t, err)
+	assert.Equal(t, "/baskets/1", basket.Self.Href)
+	assert.Equal(t, []*Link{{Href: "/baskets/2"}}, basket.Next)
+	assert.Equal(t, []Item{{Self: Link{Href: "/items/1"}, Count: 2}, {Count: 3}}, basket.Items)
+	assert.Equal(t, 3, basket.Pointers[1].Count)
+	assert.Equal(t, 2, basket.First.Count)
+	assert.Equal(t, "Sam", basket.Owner.Data["name"])
+	assert.Equal(t, "/people/1", basket.Owner.Links.Self.Href)
+	assert.Equal(t, "Sam", basket.Raw["name"])
+	assert.Equal(t, "/baskets/2", basket.Links.Relations["next"][0].Href)
+	assert.Len(t, basket.Embeds.Relations["items"], 2)
+	assert.Nil(t, basket.Absent)
+	assert.Equal(t, json.Number("1.5"), basket.Extra["weight"])
+}
+
+// selfUnmarshalingOrder decodes itself as HAL from its own UnmarshalJSON
+type selfUnmarshalingOrder struct {
+	Self  string `hal:"self"`
+	Total int    `json:"total"`
+}
+
+func (o *selfUnmarshalingOrder) UnmarshalJSON(b []byte) error {
+	return Unmarshal(b, o)
+}
+
+func TestUnmarshalStructFromUnmarshalJSON(t *testing.T) {
+	var orders []selfUnmarshalingOrder
+	err := json.Unmarshal([]byte(`[{"_links":{"self":{"href":"/orders/1"}},"total":5}]`), &orders)
+	assert.Nil(t, err)
+	assert.Equal(t, []selfUnmarshalingOrder{{Self: "/orders/1", Total: 5}}, orders)
+}
+
+func TestUnmarshalStructEmbeddedTypes(t *testing.T) {
+	type Document struct {
+		base
+		Self string `hal:"self"`
+		Name string `json:"name"`
+	}
+	doc := Document{base: base{ID: 7}, Self: "/docs/7", Name: "seven"}
+	b, err := Marshal(doc)
+	assert.Nil(t, err)
+	var decoded Document
+	assert.Nil(t, Unmarshal(b, &decoded))
+	assert.Equal(t, doc, decoded)
+
+	// State keys do not reach the hal tagged fields
+	assert.Nil(t, Unmarshal([]byte(`{"self":"/elsewhere","id":8}`), &decoded))
+	assert.Equal(t, Document{base: base{ID: 8}, Self: "/docs/7", Name: "seven"}, decoded)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := selfUnmarshalingDocument{base: base{ID: 7}, Time: created, Self: "/docs/7"}
+	b, err = Marshal(event)
+	assert.Nil(t, err)
+	var decodedEvent selfUnmarshalingDocument
+	assert.Nil(t, json.Unmarshal(b, &decodedEvent))
+	assert.Equal(t, event, decodedEvent)
+}
+
+// selfUnmarshalingDocument decodes itself as HAL and promotes fields
+type selfUnmarshalingDocument struct {
+	base
+	time.Time
+	Self string `hal:"self"`
+}
+
+func (d *selfUnmarshalingDocument) UnmarshalJSON(b []byte) error {
+	return Unmarshal(b, d)
+}
+
+func TestUnmarshalStructKeepsAbsentState(t *testing.T) {
+	order := testOrder{Currency: "EUR", Status: "new"}
+	assert.Nil(t, Unmarshal([]byte(`{"status":"paid"}`), &order))
+	assert.Equal(t, "EUR", order.Currency)
+	assert.Equal(t, "paid", order.Status)
+}
+
+func TestUnmarshalStructErrors(t *testing.T) {
+	var order testOrder
+	assert.NotNil(t, Unmarshal([]byte(`[]`), &order))
+	assert.NotNil(t, Unmarshal([]byte(`{"total":"many"}`), &order))
+	assert.NotNil(t, Unmarshal([]byte(`{}`), order))
+
+	type BadLink struct {
+		Next int `hal:"link,rel=next"`
+	}
+	var bad BadLink
+	err := Unmarshal([]byte(`{"_links":{"next":{"href":"/"}}}`), &bad)
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	// Non struct values unmarshal with encoding/json
+	var values []int
+	assert.Nil(t, Unmarshal([]byte(`[1,2]`), &values))
+	assert.Equal(t, []int{1, 2}, values)
+}
+
+func TestDecoderStrictStruct(t *testing.T) {
+	doc := `{"_links":{"self":{"title":"no href"}},"_embedded":{"ea:customer":{"_links":{"self":{"href":5}}}}}`
+	var order testOrder
+	err := NewDecoder(bytes.NewReader([]byte(doc))).Strict().Decode(&order)
+	found := pathErrors(t, err)
+	assert.ErrorIs(t, found["/_links/self"], ErrMissingHref)
+	assert.ErrorIs(t, found["/_embedded/ea:customer/_links/self/href"], ErrInvalidType)
+}
@@ -0,0 +1,694 @@
+package haljson
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// URITemplate is a parsed RFC 6570 URI Template, supporting levels 1 to 4
+type URITemplate struct {
+	raw   string
+	parts []templatePart
+	// matcher is compiled on first use by Match
+	matcherOnce sync.Once
+	matcher     *regexp.Regexp
+}
+
+// templatePart is either a literal or an expression of a URITemplate
+type templatePart struct {
+	literal    string
+	expression *TemplateExpression
+}
+
+// TemplateExpression is an expression of a URITemplate, such as {?x,y*}
+type TemplateExpression struct {
+	// Operator is the expression operator, one of "", "+", "#", ".", "/", ";", "?" or "&"
+	Operator  string
+	Variables []TemplateVariable
+	operator  *templateOperator
+}
+
+// TemplateVariable is a variable of an expression with its modifiers
+type TemplateVariable struct {
+	Name string
+	// Explode is set by the "*" modifier
+	Explode bool
+	// Prefix is the maximum length set by the ":" modifier, 0 when unset
+	Prefix int
+}
+
+// templateOperator describes how an operator expands, see RFC 6570 appendix A
+type templateOperator struct {
+	op            string
+	first         string
+	sep           string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+var templateOperators = map[byte]*templateOperator{
+	'+': {op: "+", first: "", sep: ",", allowReserved: true},
+	'#': {op: "#", first: "#", sep: ",", allowReserved: true},
+	'.': {op: ".", first: ".", sep: "."},
+	'/': {op: "/", first: "/", sep: "/"},
+	';': {op: ";", first: ";", sep: ";", named: true},
+	'?': {op: "?", first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {op: "&", first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+var simpleOperator = &templateOperator{first: "", sep: ","}
+
+// ParseURITemplate parses an RFC 6570 URI Template
+func ParseURITemplate(template string) (*URITemplate, error) {
+	t := &URITemplate{raw: template}
+	rest := template
+	offset := 0
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		literal := rest
+		if open >= 0 {
+			literal = rest[:open]
+		}
+		err := checkLiteral(literal, template, offset)
+		if err != nil {
+			return nil, err
+		}
+		if open < 0 {
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, templateError(template, offset+open, "unmatched '}'")
+		}
+		if open > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:open]})
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			return nil, templateError(template, offset+open, "unclosed expression")
+		}
+		expression, err := parseExpression(rest[open+1:open+1+end], template, offset+open+1)
+		if err != nil {
+			return nil, err
+		}
+		t.parts = append(t.parts, templatePart{expression: expression})
+		consumed := open + 1 + end + 1
+		rest = rest[consumed:]
+		offset += consumed
+	}
+	return t, nil
+}
+
+// checkLiteral checks that literal, starting at offset in template, only
+// holds the characters RFC 6570 section 2.1 allows outside expressions
+func checkLiteral(literal string, template string, offset int) error {
+	for i := 0; i < len(literal); i++ {
+		c := literal[i]
+		switch {
+		case c <= ' ' || c == 0x7f || strings.IndexByte(`"'<>\^`+"`|", c) >= 0:
+			return templateError(template, offset+i, fmt.Sprintf("invalid literal character %q", c))
+		case c == '%':
+			if i+2 >= len(literal) || !isHex(literal[i+1]) || !isHex(literal[i+2]) {
+				return templateError(template, offset+i, "invalid percent-encoding")
+			}
+			i += 2
+		}
+	}
+	return nil
+}
+
+// parseExpression parses the body of an expression starting at offset in template
+func parseExpression(body string, template string, offset int) (*TemplateExpression, error) {
+	expression := &TemplateExpression{operator: simpleOperator}
+	if body == "" {
+		return nil, templateError(template, offset, "empty expression")
+	}
+	if op, ok := templateOperators[body[0]]; ok {
+		expression.operator = op
+		expression.Operator = op.op
+		body = body[1:]
+		offset++
+	} else if strings.ContainsRune("=,!@|", rune(body[0])) {
+		return nil, templateError(template, offset, fmt.Sprintf("reserved operator %q", body[0]))
+	}
+	for _, spec := range strings.Split(body, ",") {
+		v, err := parseVarSpec(spec, template, offset)
+		if err != nil {
+			return nil, err
+		}
+		expression.Variables = append(expression.Variables, v)
+		offset += len(spec) + 1
+	}
+	return expression, nil
+}
+
+// parseVarSpec parses a variable name with its modifiers
+func parseVarSpec(spec string, template string, offset int) (TemplateVariable, error) {
+	var v TemplateVariable
+	name := spec
+	if strings.HasSuffix(spec, "*") {
+		v.Explode = true
+		name = spec[:len(spec)-1]
+	} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name = spec[:i]
+		length := spec[i+1:]
+		prefix, err := strconv.Atoi(length)
+		if err != nil || length[0] == '0' || len(length) > 4 || prefix < 1 {
+			return v, templateError(template, offset+i, fmt.Sprintf("invalid prefix length %q", length))
+		}
+		v.Prefix = prefix
+	}
+	if !validVarName(name) {
+		return v, templateError(template, offset, fmt.Sprintf("invalid variable name %q", name))
+	}
+	v.Name = name
+	return v, nil
+}
+
+// validVarName reports whether name is an RFC 6570 varname
+func validVarName(name string) bool {
+	if name == "" || name[0] == '.' || name[len(name)-1] == '.' || strings.Contains(name, "..") {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case isAlpha(c), isDigit(c), c == '_', c == '.':
+		case c == '%' && i+2 < len(name) && isHex(name[i+1]) && isHex(name[i+2]):
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// templateError reports a malformed template
+func templateError(template string, offset int, msg string) error {
+	return fmt.Errorf("%w: %s at offset %d in %q", ErrInvalidTemplate, msg, offset, template)
+}
+
+// ValidateURITemplate reports whether template is a well formed RFC 6570 URI Template
+func ValidateURITemplate(template string) error {
+	_, err := ParseURITemplate(template)
+	return err
+}
+
+// String returns the template as it was parsed
+func (t *URITemplate) String() string {
+	return t.raw
+}
+
+// Expressions returns the expressions of the template in order
+func (t *URITemplate) Expressions() []TemplateExpression {
+	var expressions []TemplateExpression
+	for _, part := range t.parts {
+		if part.expression != nil {
+			expression := *part.expression
+			expression.Variables = append([]TemplateVariable(nil), expression.Variables...)
+			expressions = append(expressions, expression)
+		}
+	}
+	return expressions
+}
+
+// Variables returns the names of the variables declared by the template, in
+// order of first appearance
+func (t *URITemplate) Variables() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range t.parts {
+		if part.expression == nil {
+			continue
+		}
+		for _, v := range part.expression.Variables {
+			if !seen[v.Name] {
+				seen[v.Name] = true
+				names = append(names, v.Name)
+			}
+		}
+	}
+	return names
+}
+
+// Match matches uri against the template and returns the values of the
+// variables it declares. Matching is the reverse of expansion and, as RFC
+// 6570 notes, is ambiguous for some templates: values are returned as
+// strings, exploded lists as []string and exploded associative arrays as
+// map[string]string. Variables not present in uri are omitted.
+func (t *URITemplate) Match(uri string) (map[string]any, bool) {
+	t.matcherOnce.Do(t.compileMatcher)
+	groups := t.matcher.FindStringSubmatch(uri)
+	if groups == nil {
+		return nil, false
+	}
+	vars := make(map[string]any)
+	i := 1
+	for _, part := range t.parts {
+		if part.expression == nil {
+			continue
+		}
+		if !part.expression.match(groups[i], vars) {
+			return nil, false
+		}
+		i++
+	}
+	return vars, true
+}
+
+// compileMatcher builds the regular expression used by Match
+func (t *URITemplate) compileMatcher() {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, part := range t.parts {
+		if part.expression == nil {
+			var literal strings.Builder
+			appendEncoded(&literal, part.literal, true)
+			b.WriteString(regexp.QuoteMeta(literal.String()))
+			continue
+		}
+		op := part.expression.operator
+		switch op.op {
+		case "":
+			b.WriteString(`([^/?#]*)`)
+		case "+":
+			b.WriteString(`(.*?)`)
+		case "#":
+			b.WriteString(`((?:#.*)?)`)
+		case "?":
+			b.WriteString(`((?:\?[^#]*)?)`)
+		default:
+			// Each value is introduced by the separator, which here equals the first character
+			sep := regexp.QuoteMeta(op.sep)
+			fmt.Fprintf(&b, `((?:%s[^/?#%s]*)*)`, sep, sep)
+		}
+	}
+	b.WriteString("$")
+	t.matcher = regexp.MustCompile(b.String())
+}
+
+// match assigns the variables of the expression from its matched text
+func (e *TemplateExpression) match(text string, vars map[string]any) bool {
+	op := e.operator
+	if text == "" {
+		return true
+	}
+	text = strings.TrimPrefix(text, op.first)
+	items := strings.Split(text, op.sep)
+	if op.named {
+		return e.matchNamed(items, vars)
+	}
+	return e.matchPositional(items, vars)
+}
+
+// matchNamed assigns name=value items to variables by name. Items naming no
+// variable belong to an exploded associative array, if there is one.
+func (e *TemplateExpression) matchNamed(items []string, vars map[string]any) bool {
+	byName := make(map[string]TemplateVariable, len(e.Variables))
+	var exploded *TemplateVariable
+	for i, v := range e.Variables {
+		byName[v.Name] = v
+		if v.Explode {
+			exploded = &e.Variables[i]
+		}
+	}
+	rest := map[string]string{}
+	for _, item := range items {
+		rawName, rawValue, _ := strings.Cut(item, "=")
+		name, err := url.PathUnescape(rawName)
+		if err != nil {
+			return false
+		}
+		value, err := url.PathUnescape(rawValue)
+		if err != nil {
+			return false
+		}
+		v, ok := byName[name]
+		if !ok {
+			if exploded == nil {
+				return false
+			}
+			rest[name] = value
+			continue
+		}
+		switch existing := vars[name].(type) {
+		case nil:
+			if v.Explode {
+				vars[name] = []string{value}
+			} else {
+				vars[name] = value
+			}
+		case []string:
+			vars[name] = append(existing, value)
+		default:
+			return false
+		}
+	}
+	if len(rest) > 0 {
+		vars[exploded.Name] = rest
+	}
+	return true
+}
+
+// matchPositional assigns items to variables in order. An exploded
+// variable receives as many items as the variables after it leave, the
+// last variable receives any items left over.
+func (e *TemplateExpression) matchPositional(items []string, vars map[string]any) bool {
+	for i, v := range e.Variables {
+		if len(items) == 0 {
+			break
+		}
+		remaining := len(e.Variables) - i - 1
+		count := 1
+		if v.Explode || remaining == 0 {
+			count = len(items) - remaining
+			if count < 1 {
+				count = 1
+			}
+		}
+		raw := items[:count]
+		items = items[count:]
+		if v.Explode && strings.Contains(strings.Join(raw, ""), "=") {
+			pairs := make(map[string]string, len(raw))
+			for _, item := range raw {
+				rawKey, rawValue, _ := strings.Cut(item, "=")
+				key, err := url.PathUnescape(rawKey)
+				if err != nil {
+					return false
+				}
+				pairs[key], err = url.PathUnescape(rawValue)
+				if err != nil {
+					return false
+				}
+			}
+			vars[v.Name] = pairs
+			continue
+		}
+		values := make([]string, 0, count)
+		for _, item := range raw {
+			value, err := url.PathUnescape(item)
+			if err != nil {
+				return false
+			}
+			values = append(values, value)
+		}
+		if v.Explode {
+			vars[v.Name] = values
+		} else {
+			vars[v.Name] = strings.Join(values, e.operator.sep)
+		}
+	}
+	return true
+}
+
+// Expand expands the template with vars. Values may be strings, other
+// scalars which are formatted with fmt, lists as slices or arrays, and
+// associative arrays as maps with string keys, which expand in key order.
+// Missing and nil variables, empty lists and empty maps are undefined.
+func (t *URITemplate) Expand(vars map[string]any) (string, error) {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.expression == nil {
+			appendEncoded(&b, part.literal, true)
+			continue
+		}
+		err := part.expression.expand(&b, vars)
+		if err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// expand writes the expansion of the expression to b
+func (e *TemplateExpression) expand(b *strings.Builder, vars map[string]any) error {
+	op := e.operator
+	first := true
+	for _, v := range e.Variables {
+		value, err := templateValue(vars[v.Name])
+		if err != nil {
+			return fmt.Errorf("variable %q: %w", v.Name, err)
+		}
+		if value.undefined() {
+			continue
+		}
+		if first {
+			b.WriteString(op.first)
+			first = false
+		} else {
+			b.WriteString(op.sep)
+		}
+		if value.isString {
+			s := value.str
+			if v.Prefix > 0 {
+				s = truncateRunes(s, v.Prefix)
+			}
+			if op.named {
+				appendEncoded(b, v.Name, true)
+				if s == "" {
+					b.WriteString(op.ifEmpty)
+					continue
+				}
+				b.WriteByte('=')
+			}
+			appendEncoded(b, s, op.allowReserved)
+			continue
+		}
+		if v.Prefix > 0 {
+			return fmt.Errorf("%w: prefix modifier used with composite variable %q", ErrInvalidTemplate, v.Name)
+		}
+		if !v.Explode {
+			if op.named {
+				appendEncoded(b, v.Name, true)
+				b.WriteByte('=')
+			}
+			for i, item := range value.items() {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				appendEncoded(b, item, op.allowReserved)
+			}
+			continue
+		}
+		if value.list != nil {
+			for i, item := range value.list {
+				if i > 0 {
+					b.WriteString(op.sep)
+				}
+				if op.named {
+					appendEncoded(b, v.Name, true)
+					if item == "" {
+						b.WriteString(op.ifEmpty)
+						continue
+					}
+					b.WriteByte('=')
+				}
+				appendEncoded(b, item, op.allowReserved)
+			}
+			continue
+		}
+		for i, pair := range value.pairs {
+			if i > 0 {
+				b.WriteString(op.sep)
+			}
+			appendEncoded(b, pair[0], op.allowReserved)
+			if op.named && pair[1] == "" {
+				b.WriteString(op.ifEmpty)
+				continue
+			}
+			b.WriteByte('=')
+			appendEncoded(b, pair[1], op.allowReserved)
+		}
+	}
+	return nil
+}
+
+// expansionValue is a variable value normalized for expansion
+type expansionValue struct {
+	isString bool
+	str      string
+	list     []string
+	pairs    [][2]string
+}
+
+// undefined reports whether the value is skipped during expansion
+func (v expansionValue) undefined() bool {
+	return !v.isString && len(v.list) == 0 && len(v.pairs) == 0
+}
+
+// items returns the values of a list, or the keys and values of pairs
+func (v expansionValue) items() []string {
+	if v.list != nil {
+		return v.list
+	}
+	items := make([]string, 0, len(v.pairs)*2)
+	for _, pair := range v.pairs {
+		items = append(items, pair[0], pair[1])
+	}
+	return items
+}
+
+// templateValue normalizes a variable value
+func templateValue(value any) (expansionValue, error) {
+	if value == nil {
+		return expansionValue{}, nil
+	}
+	switch v := value.(type) {
+	case string:
+		return expansionValue{isString: true, str: v}, nil
+	case []string:
+		return expansionValue{list: v}, nil
+	case map[string]string:
+		var pairs [][2]string
+		for _, key := range orderedKeys(v, nil, defaultEncodeOptions) {
+			pairs = append(pairs, [2]string{key, v[key]})
+		}
+		return expansionValue{pairs: pairs}, nil
+	case fmt.Stringer:
+		return expansionValue{isString: true, str: v.String()}, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return expansionValue{}, nil
+		}
+		return templateValue(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		list := make([]string, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			item, err := scalarString(rv.Index(i).Interface())
+			if err != nil {
+				return expansionValue{}, err
+			}
+			list = append(list, item)
+		}
+		return expansionValue{list: list}, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return expansionValue{}, fmt.Errorf("unsupported map key type %s", rv.Type().Key())
+		}
+		keys := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		var pairs [][2]string
+		for _, key := range keys {
+			item, err := scalarString(rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())).Interface())
+			if err != nil {
+				return expansionValue{}, err
+			}
+			pairs = append(pairs, [2]string{key, item})
+		}
+		return expansionValue{pairs: pairs}, nil
+	}
+	s, err := scalarString(value)
+	if err != nil {
+		return expansionValue{}, err
+	}
+	return expansionValue{isString: true, str: s}, nil
+}
+
+// scalarString formats a scalar value for expansion
+func scalarString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case fmt.Stringer:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.String:
+		return fmt.Sprint(value), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", value)
+}
+
+// truncateRunes returns the first n characters of s
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// appendEncoded writes s to b, percent-encoding every character that is not
+// unreserved, or when allowReserved is set not unreserved, reserved or an
+// existing pct-encoded triplet
+func appendEncoded(b *strings.Builder, s string, allowReserved bool) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isUnreserved(c):
+			b.WriteByte(c)
+		case allowReserved && isReserved(c):
+			b.WriteByte(c)
+		case allowReserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			// Multi-byte characters are encoded byte by byte, as UTF-8
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isUnreserved(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isReserved(c byte) bool {
+	return strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0
+}
+
+// Expand expands the href of a templated link with vars, see URITemplate.Expand
+func (l *Link) Expand(vars map[string]any) (string, error) {
+	if !l.Templated {
+		return "", ErrNotTemplated
+	}
+	t, err := ParseURITemplate(l.Href)
+	if err != nil {
+		return "", err
+	}
+	return t.Expand(vars)
+}
+
+// Expand expands the href of a templated curie for the relation name rel
+func (c *Curie) Expand(rel string) (string, error) {
+	if !c.Templated {
+		return "", ErrNotTemplated
+	}
+	t, err := ParseURITemplate(c.Href)
+	if err != nil {
+		return "", err
+	}
+	return t.Expand(map[string]any{"rel": rel})
+}
@@ -0,0 +1,330 @@
+package haljson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6570Vars are the variables used by the examples of RFC 6570 section 3.2
+var rfc6570Vars = map[string]any{
+	"count":      []string{"one", "two", "three"},
+	"dom":        []string{"example", "com"},
+	"dub":        "me/too",
+	"hello":      "Hello World!",
+	"half":       "50%",
+	"var":        "value",
+	"who":        "fred",
+	"base":       "http://example.com/home/",
+	"path":       "/foo/bar",
+	"list":       []string{"red", "green", "blue"},
+	"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+	"v":          "6",
+	"x":          "1024",
+	"y":          "768",
+	"empty":      "",
+	"empty_keys": map[string]string{},
+	"undef":      nil,
+}
+
+func TestURITemplateExpandRFC6570(t *testing.T) {
+	// Examples from RFC 6570 sections 1.2 and 3.2, maps expand in key order
+	cases := map[string]string{
+		// Level 1
+		"{var}":     "value",
+		"{hello}":   "Hello%20World%21",
+		"{half}":    "50%25",
+		"O{empty}X": "OX",
+		"O{undef}X": "OX",
+		// Level 2
+		"{+var}":           "value",
+		"{+hello}":         "Hello%20World!",
+		"{+path}/here":     "/foo/bar/here",
+		"here?ref={+path}": "here?ref=/foo/bar",
+		"X{#var}":          "X#value",
+		"X{#hello}":        "X#Hello%20World!",
+		"{+half}":          "50%25",
+		"{base}index":      "http%3A%2F%2Fexample.com%2Fhome%2Findex",
+		"{+base}index":     "http://example.com/home/index",
+		// Level 3
+		"map?{x,y}":         "map?1024,768",
+		"{x,hello,y}":       "1024,Hello%20World%21,768",
+		"{+x,hello,y}":      "1024,Hello%20World!,768",
+		"{+path,x}/here":    "/foo/bar,1024/here",
+		"{#x,hello,y}":      "#1024,Hello%20World!,768",
+		"{#path,x}/here":    "#/foo/bar,1024/here",
+		"X{.var}":           "X.value",
+		"X{.x,y}":           "X.1024.768",
+		"{/var}":            "/value",
+		"{/var,x}/here":     "/value/1024/here",
+		"{;x,y}":            ";x=1024;y=768",
+		"{;x,y,empty}":      ";x=1024;y=768;empty",
+		"{?x,y}":            "?x=1024&y=768",
+		"{?x,y,empty}":      "?x=1024&y=768&empty=",
+		"?fixed=yes{&x}":    "?fixed=yes&x=1024",
+		"{&x,y,empty}":      "&x=1024&y=768&empty=",
+		"{var:3}":           "val",
+		"{var:30}":          "value",
+		"{list}":            "red,green,blue",
+		"{list*}":           "red,green,blue",
+		"{keys}":            "comma,%2C,dot,.,semi,%3B",
+		"{keys*}":           "comma=%2C,dot=.,semi=%3B",
+		"{+path:6}/here":    "/foo/b/here",
+		"{+list}":           "red,green,blue",
+		"{+list*}":          "red,green,blue",
+		"{+keys}":           "comma,,,dot,.,semi,;",
+		"{+keys*}":          "comma=,,dot=.,semi=;",
+		"{#path:6}/here":    "#/foo/b/here",
+		"{#list}":           "#red,green,blue",
+		"{#list*}":          "#red,green,blue",
+		"{#keys}":           "#comma,,,dot,.,semi,;",
+		"{#keys*}":          "#comma=,,dot=.,semi=;",
+		"X{.var:3}":         "X.val",
+		"X{.list}":          "X.red,green,blue",
+		"X{.list*}":         "X.red.green.blue",
+		"X{.keys}":          "X.comma,%2C,dot,.,semi,%3B",
+		"X{.keys*}":         "X.comma=%2C.dot=..semi=%3B",
+		"X{.empty_keys}":    "X",
+		"X{.empty_keys*}":   "X",
+		"{/var:1,var}":      "/v/value",
+		"{/list}":           "/red,green,blue",
+		"{/list*}":          "/red/green/blue",
+		"{/list*,path:4}":   "/red/green/blue/%2Ffoo",
+		"{/keys}":           "/comma,%2C,dot,.,semi,%3B",
+		"{/keys*}":          "/comma=%2C/dot=./semi=%3B",
+		"{;hello:5}":        ";hello=Hello",
+		"{;list}":           ";list=red,green,blue",
+		"{;list*}":          ";list=red;list=green;list=blue",
+		"{;keys}":           ";keys=comma,%2C,dot,.,semi,%3B",
+		"{;keys*}":          ";comma=%2C;dot=.;semi=%3B",
+		"{?var:3}":          "?var=val",
+		"{?list}":           "?list=red,green,blue",
+		"{?list*}":          "?list=red&list=green&list=blue",
+		"{?keys}":           "?keys=comma,%2C,dot,.,semi,%3B",
+		"{?keys*}":          "?comma=%2C&dot=.&semi=%3B",
+		"{&var:3}":          "&var=val",
+		"{&list}":           "&list=red,green,blue",
+		"{&list*}":          "&list=red&list=green&list=blue",
+		"{&keys}":           "&keys=comma,%2C,dot,.,semi,%3B",
+		"{&keys*}":          "&comma=%2C&dot=.&semi=%3B",
+		"{count}":           "one,two,three",
+		"{count*}":          "one,two,three",
+		"{/count}":          "/one,two,three",
+		"{/count*}":         "/one/two/three",
+		"{;count}":          ";count=one,two,three",
+		"{;count*}":         ";count=one;count=two;count=three",
+		"{?count}":          "?count=one,two,three",
+		"{?count*}":         "?count=one&count=two&count=three",
+		"{&count*}":         "&count=one&count=two&count=three",
+		"www{.dom*}":        "www.example.com",
+		"{var}/{who}{?dub}": "value/fred?dub=me%2Ftoo",
+		"{;v,empty,who}":    ";v=6;empty;who=fred",
+		"{?v,undef,who}":    "?v=6&who=fred",
+		"/orders{?id,page}": "/orders",
+	}
+	for template, expected := range cases {
+		parsed, err := ParseURITemplate(template)
+		if !assert.Nil(t, err, template) {
+			continue
+		}
+		actual, err := parsed.Expand(rfc6570Vars)
+		assert.Nil(t, err, template)
+		assert.Equal(t, expected, actual, template)
+	}
+}
+
+func TestURITemplateExpandValues(t *testing.T) {
+	template, err := ParseURITemplate("/orders{/id}{?page,size,active,ratio,tags,filter*}")
+	assert.Nil(t, err)
+	assert.Equal(t, "/orders{/id}{?page,size,active,ratio,tags,filter*}", template.String())
+
+	expanded, err := template.Expand(map[string]any{
+		"id":     42,
+		"page":   uint8(2),
+		"active": true,
+		"ratio":  0.5,
+		"tags":   []any{"a b", 3},
+		"filter": map[string]any{"status": "shipped", "total": 10},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "/orders/42?page=2&active=true&ratio=0.5&tags=a%20b,3&status=shipped&total=10", expanded)
+
+	expanded, err = template.Expand(map[string]any{"id": "café", "size": (*string)(nil)})
+	assert.Nil(t, err)
+	assert.Equal(t, "/orders/caf%C3%A9", expanded)
+
+	_, err = template.Expand(map[string]any{"id": struct{}{}})
+	assert.NotNil(t, err)
+	_, err = template.Expand(map[string]any{"tags": []any{[]int{1}}})
+	assert.NotNil(t, err)
+	_, err = template.Expand(map[string]any{"filter": map[int]string{1: "a"}})
+	assert.NotNil(t, err)
+
+	prefixed, err := ParseURITemplate("{list:2}")
+	assert.Nil(t, err)
+	_, err = prefixed.Expand(map[string]any{"list": []string{"a"}})
+	assert.True(t, errors.Is(err, ErrInvalidTemplate))
+}
+
+func TestParseURITemplateErrors(t *testing.T) {
+	for _, template := range []string{
+		"/orders{",
+		"/orders}",
+		"/orders{id",
+		"/orders{{id}}",
+		"{}",
+		"{=id}",
+		"{!id}",
+		"{id:0}",
+		"{id:10000}",
+		"{id:x}",
+		"{i d}",
+		"{.}",
+		"{a..b}",
+		"{id,}",
+		"{%zz}",
+		"/a b{x}",
+		"/tab\t{x}",
+		"/\x7f",
+		`/"quoted"`,
+		"/it's",
+		"100%{x}",
+		"/%zz",
+		"/%4",
+		"/<a>",
+		`/back\slash`,
+		"/a^b",
+		"/a`b",
+		"/a|b",
+	} {
+		_, err := ParseURITemplate(template)
+		assert.True(t, errors.Is(err, ErrInvalidTemplate), "expected %q to be invalid, got %v", template, err)
+	}
+
+	for _, template := range []string{"", "/plain", "{a.b,c_d,%20e}", "{x:9999}", "/a%20b/%7E{x}", "/café/?q=a,b;c=d&e=f#g{x}"} {
+		_, err := ParseURITemplate(template)
+		assert.Nil(t, err, template)
+	}
+}
+
+func TestURITemplateLiterals(t *testing.T) {
+	template, err := ParseURITemplate("/a%20b/%41/café{?x}")
+	assert.Nil(t, err)
+	expanded, err := template.Expand(map[string]any{"x": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/a%20b/%41/caf%C3%A9?x=1", expanded)
+}
+
+func TestLinkExpand(t *testing.T) {
+	link := &Link{Href: "/orders{?id,page}", Templated: true}
+	href, err := link.Expand(map[string]any{"id": 5})
+	assert.Nil(t, err)
+	assert.Equal(t, "/orders?id=5", href)
+
+	_, err = (&Link{Href: "/orders"}).Expand(nil)
+	assert.Equal(t, ErrNotTemplated, err)
+
+	_, err = (&Link{Href: "/orders{?id", Templated: true}).Expand(nil)
+	assert.True(t, errors.Is(err, ErrInvalidTemplate))
+}
+
+func TestCurieExpand(t *testing.T) {
+	curie := &Curie{Name: "ea", Href: "http://example.com/docs/rels/{rel}", Templated: true}
+	href, err := curie.Expand("orders")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://example.com/docs/rels/orders", href)
+
+	_, err = (&Curie{Href: "/docs"}).Expand("orders")
+	assert.Equal(t, ErrNotTemplated, err)
+
+	_, err = (&Curie{Href: "/docs/{rel", Templated: true}).Expand("orders")
+	assert.True(t, errors.Is(err, ErrInvalidTemplate))
+}
+
+func TestURITemplateIntrospection(t *testing.T) {
+	template, err := ParseURITemplate("/orders{/id}{?page,size:3,id}{&filter*}")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "page", "size", "filter"}, template.Variables())
+	assert.Equal(t, []TemplateExpression{
+		{Operator: "/", Variables: []TemplateVariable{{Name: "id"}}, operator: templateOperators['/']},
+		{Operator: "?", Variables: []TemplateVariable{{Name: "page"}, {Name: "size", Prefix: 3}, {Name: "id"}}, operator: templateOperators['?']},
+		{Operator: "&", Variables: []TemplateVariable{{Name: "filter", Explode: true}}, operator: templateOperators['&']},
+	}, template.Expressions())
+
+	plain, err := ParseURITemplate("/orders")
+	assert.Nil(t, err)
+	assert.Empty(t, plain.Variables())
+	assert.Empty(t, plain.Expressions())
+
+	assert.Nil(t, ValidateURITemplate("/orders{?id}"))
+	assert.True(t, errors.Is(ValidateURITemplate("/orders{?id"), ErrInvalidTemplate))
+}
+
+func TestURITemplateMatch(t *testing.T) {
+	cases := []struct {
+		template string
+		uri      string
+		expected map[string]any
+	}{
+		{"/orders/{id}", "/orders/42", map[string]any{"id": "42"}},
+		{"/orders/{id}", "/orders/caf%C3%A9", map[string]any{"id": "café"}},
+		{"/orders{?id,page}", "/orders?page=2&id=5", map[string]any{"id": "5", "page": "2"}},
+		{"/orders{?id,page}", "/orders", map[string]any{}},
+		{"/orders{?id,page}", "/orders?id=", map[string]any{"id": ""}},
+		{"/orders{?list*}", "/orders?list=red&list=green", map[string]any{"list": []string{"red", "green"}}},
+		{"/orders{?keys*}", "/orders?semi=%3B&dot=.", map[string]any{"keys": map[string]string{"semi": ";", "dot": "."}}},
+		{"{+path}/here", "/foo/bar/here", map[string]any{"path": "/foo/bar"}},
+		{"{/list*}", "/red/green/blue", map[string]any{"list": []string{"red", "green", "blue"}}},
+		{"{/var,x}/here", "/value/1024/here", map[string]any{"var": "value", "x": "1024"}},
+		{"map?{x,y}", "map?1024,768", map[string]any{"x": "1024", "y": "768"}},
+		{"{list}", "red,green,blue", map[string]any{"list": "red,green,blue"}},
+		{"X{.x,y}", "X.1024.768", map[string]any{"x": "1024", "y": "768"}},
+		{"{;x,y,empty}", ";x=1024;y=768;empty", map[string]any{"x": "1024", "y": "768", "empty": ""}},
+		{"{/keys*}", "/comma=%2C/dot=.", map[string]any{"keys": map[string]string{"comma": ",", "dot": "."}}},
+		{"X{#var}", "X#value", map[string]any{"var": "value"}},
+		{"/docs/rels/{rel}", "/docs/rels/orders", map[string]any{"rel": "orders"}},
+	}
+	for _, c := range cases {
+		template, err := ParseURITemplate(c.template)
+		assert.Nil(t, err)
+		vars, ok := template.Match(c.uri)
+		assert.True(t, ok, "%s should match %s", c.template, c.uri)
+		assert.Equal(t, c.expected, vars, "%s matching %s", c.template, c.uri)
+	}
+
+	for _, c := range [][2]string{
+		{"/orders/{id}", "/customers/42"},
+		{"/orders/{id}", "/orders/42/items"},
+		{"/orders{?id}", "/orders?other=1"},
+		{"/orders{?id}", "/orders?id=%zz"},
+		{"/orders{/id}/item", "/orders/1/2"},
+	} {
+		parsed, err := ParseURITemplate(c[0])
+		assert.Nil(t, err)
+		_, ok := parsed.Match(c[1])
+		assert.False(t, ok, "%s should not match %s", c[0], c[1])
+	}
+}
+
+func TestURITemplateMatchRoundTrip(t *testing.T) {
+	template, err := ParseURITemplate("/orders{/id}{?page,size}")
+	assert.Nil(t, err)
+	vars := map[string]any{"id": "a b/c", "page": "2", "size": "10"}
+	expanded, err := template.Expand(vars)
+	assert.Nil(t, err)
+	matched, ok := template.Match(expanded)
+	assert.True(t, ok)
+	assert.Equal(t, vars, matched)
+}
+
+func TestAddLinkValidatesTemplates(t *testing.T) {
+	links := NewLinks()
+	err := links.AddLink("find", &Link{Href: "/orders{?id", Templated: true})
+	assert.True(t, errors.Is(err, ErrInvalidTemplate))
+	assert.NotContains(t, links.Relations, "find")
+
+	assert.Nil(t, links.AddLink("find", &Link{Href: "/orders{?id}", Templated: true}))
+	// Hrefs that are not templated are not URI Templates
+	assert.Nil(t, links.AddLink("literal", &Link{Href: "/orders{"}))
+}
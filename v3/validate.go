@@ -0,0 +1,167 @@
+package haljson
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// stateHolder is implemented by resources whose state keys can be checked
+type stateHolder interface {
+	stateKeys() []string
+}
+
+// stateKeys returns the keys of the data of the resource
+func (r *Resource[T]) stateKeys() []string {
+	return orderedKeys(r.Data, nil, defaultEncodeOptions)
+}
+
+// stateKeys returns the keys of the encoded state of the resource, or none
+// when it does not encode as an object
+func (r *StateResource[S]) stateKeys() []string {
+	b, err := json.Marshal(r.State)
+	if err != nil {
+		return nil
+	}
+	members, err := splitObject(b)
+	if err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(members))
+	for _, m := range members {
+		keys = append(keys, m.key)
+	}
+	return keys
+}
+
+// Validate checks r and its embedded resources and returns every problem
+// found, joined with errors.Join. Each problem is a *PathError holding the
+// JSON Pointer of the offending value and one of ErrMissingHref,
+// ErrInvalidTemplate, ErrMissingCurieName, ErrMissingRelVariable,
+// ErrDuplicateCurie, ErrNoCurie, ErrReservedKey or ErrDuplicateName. CURIE
+// relations may use the curies of the resources embedding theirs. Validate
+// does not modify r, it may run alongside other readers such as MarshalJSON.
+func (r *Resource[T]) Validate() error {
+	return validate(r)
+}
+
+// Validate checks r and its embedded resources, as Resource.Validate does
+func (r *StateResource[S]) Validate() error {
+	return validate(r)
+}
+
+// validate checks root and its embedded resources. The WalkFunc changes
+// no node, so Walk writes nothing back.
+func validate(root resourceNode) error {
+	var errs []error
+	report := func(path string, err error) {
+		errs = append(errs, &PathError{Path: path, Err: err})
+	}
+	// inherited holds the curies in scope for the resources embedded at
+	// each depth, as Walk visits embedded resources depth first
+	inherited := [][]Curie{nil}
+	if links := root.links(); links != nil {
+		inherited[0] = links.parent.curieScope()
+	}
+	_ = Walk(root, func(node *Node) error {
+		switch node.Kind {
+		case ResourceNode:
+			scope := inherited[node.Depth]
+			resource, ok := node.Resource.(resourceNode)
+			if ok && !isNil(resource) {
+				if links := resource.links(); links != nil {
+					scope = append(append([]Curie(nil), links.Curies...), scope...)
+				}
+				validateResource(resource, scope, node.Path, report)
+			}
+			inherited = append(inherited[:node.Depth+1], scope)
+		case LinkNode:
+			validateLink(node.Link, node.Path, report)
+		case CurieNode:
+			validateCurie(node.Curie, node.Path, report)
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// validateResource checks what concerns a resource as a whole: reserved
+// state keys, duplicate curie names, CURIE relations whose curie is not in
+// scope and duplicate link names in a relation
+func validateResource(r resourceNode, scope []Curie, path string, report func(string, error)) {
+	if holder, ok := r.(stateHolder); ok {
+		for _, key := range holder.stateKeys() {
+			if key == LINKS || key == EMBEDDED {
+				report(pointer(path, key), ErrReservedKey)
+			}
+		}
+	}
+	links := r.links()
+	if links == nil {
+		return
+	}
+	linksPath := pointer(path, LINKS)
+	curies := make(map[string]bool, len(links.Curies))
+	for i, curie := range links.Curies {
+		if curie.Name == "" {
+			continue
+		}
+		if curies[curie.Name] {
+			report(pointer(index(pointer(linksPath, CURIES), i), NAME), ErrDuplicateCurie)
+		}
+		curies[curie.Name] = true
+	}
+	for _, rel := range links.Rels() {
+		if prefix, ok := curiePrefix(rel); ok && !hasCurie(scope, prefix) {
+			report(pointer(linksPath, rel), ErrNoCurie)
+		}
+		names := make(map[string]bool)
+		for i, link := range links.Relations[rel] {
+			if link == nil || link.Name == "" {
+				continue
+			}
+			if names[link.Name] {
+				report(pointer(index(pointer(linksPath, rel), i), NAME), ErrDuplicateName)
+			}
+			names[link.Name] = true
+		}
+	}
+}
+
+// validateLink checks the href of a link found at path
+func validateLink(link *Link, path string, report func(string, error)) {
+	if link.Href == "" {
+		report(pointer(path, HREF), ErrMissingHref)
+		return
+	}
+	if link.Templated {
+		err := ValidateURITemplate(link.Href)
+		if err != nil {
+			report(pointer(path, HREF), err)
+		}
+	}
+}
+
+// validateCurie checks the name and href of a curie found at path
+func validateCurie(curie *Curie, path string, report func(string, error)) {
+	if curie.Name == "" {
+		report(pointer(path, NAME), ErrMissingCurieName)
+	}
+	if curie.Href == "" {
+		report(pointer(path, HREF), ErrMissingHref)
+		return
+	}
+	if !curie.Templated {
+		return
+	}
+	template, err := ParseURITemplate(curie.Href)
+	if err != nil {
+		report(pointer(path, HREF), err)
+		return
+	}
+	for _, name := range template.Variables() {
+		if name == "rel" {
+			return
+		}
+	}
+	report(pointer(path, HREF), ErrMissingRelVariable)
+}
@@ -0,0 +1,79 @@
+package haljson
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateValid(t *testing.T) {
+	assert.Nil(t, testTree().Validate())
+	assert.Nil(t, NewResource[any]().Validate())
+}
+
+func TestValidate(t *testing.T) {
+	order := NewResource[any]()
+	order.Links.Relations["item"] = []*Link{{Href: "/items/1", Name: "a"}, {Href: "/items/2", Name: "a"}}
+	order.Links.Curies = []Curie{{Href: "/docs/{rel}", Templated: true}}
+	order.Data[LINKS] = "oops"
+
+	r := NewResource[any]()
+	r.Links.Self = &Link{}
+	r.Links.Curies = []Curie{
+		{Name: "ea", Href: "/docs/{rel}", Templated: true},
+		{Name: "ea", Href: "/docs/{rel}", Templated: true},
+		{Name: "xx", Href: "/docs/{name}", Templated: true},
+		{Name: "yy", Href: "/docs/{rel", Templated: true},
+	}
+	r.Links.Relations["find"] = []*Link{{Href: "/orders{?id", Templated: true}}
+	r.Embeds.add("order", *order)
+	state := NewStateResource(map[string]any{EMBEDDED: 1})
+	r.AddEmbed("state", state)
+
+	err := r.Validate()
+	found := pathErrors(t, err)
+	assert.Len(t, found, 9)
+	assert.Equal(t, ErrMissingHref, found["/_links/self/href"])
+	assert.Equal(t, ErrDuplicateCurie, found["/_links/curies/1/name"])
+	assert.Equal(t, ErrMissingRelVariable, found["/_links/curies/2/href"])
+	assert.ErrorIs(t, found["/_links/curies/3/href"], ErrInvalidTemplate)
+	assert.ErrorIs(t, found["/_links/find/0/href"], ErrInvalidTemplate)
+	assert.Equal(t, ErrReservedKey, found["/_embedded/order/0/_links"])
+	assert.Equal(t, ErrMissingCurieName, found["/_embedded/order/0/_links/curies/0/name"])
+	assert.Equal(t, ErrDuplicateName, found["/_embedded/order/0/_links/item/1/name"])
+	assert.Equal(t, ErrReservedKey, found["/_embedded/state/0/_embedded"])
+
+	assert.True(t, errors.Is(err, ErrDuplicateName))
+	var pathErr *PathError
+	assert.True(t, errors.As(err, &pathErr))
+
+	// Validating does not change the resource
+	assert.Len(t, r.Embeds.Relations["order"], 1)
+	assert.Len(t, r.Links.Curies, 4)
+}
+
+func TestValidateConcurrentMarshal(t *testing.T) {
+	r := testTree()
+	r.AddEmbed("customer", testCustomer{Self: "/customers/1", Name: "Jane"})
+	expected, err := r.MarshalJSON()
+	assert.Nil(t, err)
+
+	// Run with -race, Validate only reads the resource
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, r.Validate())
+		}()
+		go func() {
+			defer wg.Done()
+			b, err := r.MarshalJSON()
+			assert.Nil(t, err)
+			assert.Equal(t, string(expected), string(b))
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,239 @@
+package haljson
+
+import (
+	"errors"
+	"reflect"
+)
+
+// NodeKind identifies what a Node visited by Walk holds
+type NodeKind int
+
+const (
+	// ResourceNode is an embedded resource, or the resource Walk started from
+	ResourceNode NodeKind = iota
+	// LinkNode is a link, including the self link
+	LinkNode
+	// CurieNode is a curie
+	CurieNode
+)
+
+// Node is a resource, link or curie visited by Walk. A WalkFunc replaces
+// the node by setting Resource, Link or Curie, or removes it by setting
+// them to nil. The resource Walk started from cannot be replaced.
+type Node struct {
+	Kind NodeKind
+	// Path is the JSON Pointer of the node within the document
+	Path string
+	// Rel is the relation holding the node, "self" for the self link,
+	// "curies" for curies and empty for the resource Walk started from
+	Rel string
+	// Index is the position of the node within Rel
+	Index int
+	// Depth is the number of resources embedding the node, links and curies
+	// have the depth of their resource
+	Depth int
+	// Resource is the resource when Kind is ResourceNode, a *Resource[any]
+	// for embedded resources held in Embeds.Relations or the value that was
+	// embedded otherwise
+	Resource any
+	// Link is the link when Kind is LinkNode
+	Link *Link
+	// Curie is the curie when Kind is CurieNode
+	Curie *Curie
+}
+
+// WalkFunc is called by Walk for every node. Returning SkipResource skips
+// what remains of the current resource, returning SkipAll stops the walk
+// and any other error stops it and is returned by Walk.
+type WalkFunc func(node *Node) error
+
+// SkipResource is returned by a WalkFunc to skip a resource. Returned for a
+// resource it skips its links, curies and embedded resources, returned for
+// a link or curie it skips the rest of the resource holding it.
+var SkipResource = errors.New("skip this resource")
+
+// SkipAll is returned by a WalkFunc to stop the walk without an error
+var SkipAll = errors.New("skip everything")
+
+// Walk calls fn for root, then for its self link, curies and the links of
+// every relation, then walks its embedded resources in turn. Relations are
+// visited in sorted order. Embedded values that are not a Resource or
+// StateResource, such as structs with `hal` tags, are visited but not
+// walked into.
+func Walk(root resourceNode, fn WalkFunc) error {
+	node := &Node{Kind: ResourceNode, Resource: root}
+	err := fn(node)
+	if err == nil {
+		err = walkResource(root, node, fn)
+	}
+	if err == SkipResource || err == SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkResource walks the links, curies and embedded resources of r, which
+// was visited as parent
+func walkResource(r resourceNode, parent *Node, fn WalkFunc) error {
+	err := walkLinks(r.links(), parent, fn)
+	if err != nil {
+		return err
+	}
+	if walker, ok := r.(embedsWalker); ok {
+		return walker.walkEmbeds(parent, fn)
+	}
+	e := r.embeds()
+	return walkEmbeds(e, parent, fn, e.Replace)
+}
+
+// embedsWalker is implemented by resources that walk their embedded
+// resources themselves, such as Collection whose items are not held in its
+// Embeds
+type embedsWalker interface {
+	walkEmbeds(parent *Node, fn WalkFunc) error
+}
+
+// walkLinks visits the links and curies of l
+func walkLinks(l *Links, parent *Node, fn WalkFunc) error {
+	if l == nil {
+		return nil
+	}
+	linksPath := pointer(parent.Path, LINKS)
+	if l.Self != nil {
+		node := &Node{Kind: LinkNode, Path: pointer(linksPath, SELF), Rel: SELF, Depth: parent.Depth, Link: l.Self}
+		err := fn(node)
+		// Only changes are written back, so that visitors that do not
+		// change anything can run alongside readers of the tree
+		if node.Link != l.Self {
+			l.Self = node.Link
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(l.Curies) > 0 {
+		var curies []Curie
+		changed := false
+		var err error
+		for i := range l.Curies {
+			node := &Node{Kind: CurieNode, Path: index(pointer(linksPath, CURIES), i), Rel: CURIES, Index: i, Depth: parent.Depth, Curie: &l.Curies[i]}
+			if err == nil {
+				err = fn(node)
+			}
+			changed = changed || node.Curie != &l.Curies[i]
+			if node.Curie != nil {
+				curies = append(curies, *node.Curie)
+			}
+		}
+		if changed {
+			l.Curies = curies
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range orderedKeys(l.Relations, nil, defaultEncodeOptions) {
+		relPath := pointer(linksPath, rel)
+		single := l.Cardinality(rel) == Single && len(l.Relations[rel]) == 1
+		var links []*Link
+		changed := false
+		var err error
+		for i, link := range l.Relations[rel] {
+			if link == nil || err != nil {
+				links = append(links, link)
+				continue
+			}
+			node := &Node{Kind: LinkNode, Path: index(relPath, i), Rel: rel, Index: i, Depth: parent.Depth, Link: link}
+			if single {
+				node.Path = relPath
+			}
+			err = fn(node)
+			changed = changed || node.Link != link
+			if node.Link != nil {
+				links = append(links, node.Link)
+			}
+		}
+		switch {
+		case !changed:
+		case len(links) == 0:
+			l.Remove(rel)
+		default:
+			l.Relations[rel] = links
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkEmbeds visits and walks the embedded resources of e. The embedded
+// resources of a relation the WalkFunc changed are written back by replace.
+func walkEmbeds(e *Embeds, parent *Node, fn WalkFunc, replace func(rel string, embeds ...any) error) error {
+	if e == nil {
+		return nil
+	}
+	embeddedPath := pointer(parent.Path, EMBEDDED)
+	for _, rel := range e.Rels() {
+		relPath := pointer(embeddedPath, rel)
+		single := e.Cardinality(rel) == Single && e.count(rel) == 1
+		embeds := e.Get(rel)
+		if len(embeds) == 0 {
+			continue
+		}
+		var replaced []any
+		changed := false
+		var err error
+		for i, embed := range embeds {
+			if err != nil {
+				replaced = append(replaced, embed)
+				continue
+			}
+			node := &Node{Kind: ResourceNode, Path: index(relPath, i), Rel: rel, Index: i, Depth: parent.Depth + 1, Resource: embed}
+			if single {
+				node.Path = relPath
+			}
+			err = fn(node)
+			if err == nil {
+				if child, ok := node.Resource.(resourceNode); ok && !isNil(child) {
+					err = walkResource(child, node, fn)
+				}
+			}
+			if err == SkipResource {
+				err = nil
+			}
+			changed = changed || !sameEmbed(node.Resource, embed)
+			if !isNil(node.Resource) {
+				replaced = append(replaced, node.Resource)
+			}
+		}
+		if changed {
+			// Replacing keeps the position and cardinality of rel
+			replaceErr := replace(rel, replaced...)
+			if err == nil {
+				err = replaceErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameEmbed reports whether a and b are the same embedded value. Pointers,
+// maps and slices are the same when they point to the same value, other
+// values when they are deeply equal, a WalkFunc cannot change them in place.
+func sameEmbed(a, b any) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() {
+		return !va.IsValid() && !vb.IsValid()
+	}
+	switch va.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		return va.Pointer() == vb.Pointer()
+	}
+	return reflect.DeepEqual(a, b)
+}
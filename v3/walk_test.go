@@ -0,0 +1,155 @@
+package haljson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTree returns a resource embedding an order, which embeds a customer
+func testTree() *Resource[any] {
+	customer := NewResource[any]()
+	customer.Self("/customers/7809")
+	customer.Data["name"] = "Jane"
+
+	order := NewResource[any]()
+	order.Self("/orders/1")
+	order.AddLink("item", &Link{Href: "/items/1"})
+	order.AddLink("item", &Link{Href: "/items/2"})
+	order.SetEmbed("customer", customer)
+
+	r := NewResource[any]()
+	r.Self("/orders")
+	r.AddCurie(&Curie{Name: "ea", Href: "/docs/{rel}", Templated: true})
+	r.AddLink("next", &Link{Href: "/orders?page=2"}, Single)
+	r.AddEmbed("order", order)
+	r.AddEmbed("order", map[string]any{"total": 2})
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	var visited []string
+	err := Walk(testTree(), func(node *Node) error {
+		switch node.Kind {
+		case ResourceNode:
+			visited = append(visited, fmt.Sprintf("resource %q %s %d", node.Path, node.Rel, node.Depth))
+		case LinkNode:
+			visited = append(visited, fmt.Sprintf("link %s %s %d %d", node.Path, node.Link.Href, node.Index, node.Depth))
+		case CurieNode:
+			visited = append(visited, fmt.Sprintf("curie %s %s", node.Path, node.Curie.Name))
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		`resource ""  0`,
+		"link /_links/self /orders 0 0",
+		"curie /_links/curies/0 ea",
+		"link /_links/next /orders?page=2 0 0",
+		`resource "/_embedded/order/0" order 1`,
+		"link /_embedded/order/0/_links/self /orders/1 0 1",
+		"link /_embedded/order/0/_links/item/0 /items/1 0 1",
+		"link /_embedded/order/0/_links/item/1 /items/2 1 1",
+		`resource "/_embedded/order/0/_embedded/customer" customer 2`,
+		"link /_embedded/order/0/_embedded/customer/_links/self /customers/7809 0 2",
+		`resource "/_embedded/order/1" order 1`,
+	}, visited)
+}
+
+func TestWalkSkipAndStop(t *testing.T) {
+	var visited []string
+	err := Walk(testTree(), func(node *Node) error {
+		visited = append(visited, node.Path)
+		if node.Rel == "order" && node.Index == 0 {
+			return SkipResource
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "/_links/self", "/_links/curies/0", "/_links/next", "/_embedded/order/0", "/_embedded/order/1"}, visited)
+
+	visited = nil
+	err = Walk(testTree(), func(node *Node) error {
+		visited = append(visited, node.Path)
+		if node.Rel == SELF && node.Depth == 1 {
+			return SkipResource
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, visited, "/_embedded/order/1")
+	assert.NotContains(t, visited, "/_embedded/order/0/_links/item/0", "skipping from a link skips the rest of its resource")
+
+	visited = nil
+	err = Walk(testTree(), func(node *Node) error {
+		visited = append(visited, node.Path)
+		if node.Kind == CurieNode {
+			return SkipAll
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"", "/_links/self", "/_links/curies/0"}, visited)
+
+	failure := errors.New("failure")
+	err = Walk(testTree(), func(node *Node) error {
+		if node.Depth == 2 {
+			return failure
+		}
+		return nil
+	})
+	assert.Equal(t, failure, err)
+}
+
+func TestWalkReadOnly(t *testing.T) {
+	r := testTree()
+	r.AddEmbed("customer", testCustomer{Self: "/customers/1", Name: "Jane"})
+	curies := r.Links.Curies
+	next := r.Links.Relations["next"]
+	orders := r.Embeds.Values["order"]
+	customers := r.Embeds.Values["customer"]
+
+	// A visitor that changes nothing leaves the tree as it was
+	assert.Nil(t, Walk(r, func(node *Node) error { return nil }))
+	assert.Same(t, &curies[0], &r.Links.Curies[0])
+	assert.Same(t, &next[0], &r.Links.Relations["next"][0])
+	assert.Same(t, &orders[0], &r.Embeds.Values["order"][0])
+	assert.Same(t, &customers[0], &r.Embeds.Values["customer"][0])
+	assert.Equal(t, Single, r.Links.Cardinality("next"))
+}
+
+func TestWalkReplace(t *testing.T) {
+	r := testTree()
+	err := Walk(r, func(node *Node) error {
+		switch {
+		case node.Kind == LinkNode && node.Rel == "item" && node.Index == 0:
+			// Redact a link
+			node.Link = nil
+		case node.Kind == LinkNode && node.Rel == "next":
+			node.Link = &Link{Href: "/orders?cursor=abc"}
+		case node.Kind == CurieNode:
+			node.Curie = nil
+		case node.Kind == ResourceNode && node.Rel == "customer":
+			replacement := NewResource[any]()
+			replacement.Self("/customers/redacted")
+			node.Resource = replacement
+		case node.Kind == ResourceNode && node.Rel == "order" && node.Index == 1:
+			node.Resource = nil
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+
+	b, err := json.Marshal(r)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"_links": {"self": {"href": "/orders"}, "next": {"href": "/orders?cursor=abc"}},
+		"_embedded": {"order": [{
+			"_links": {"self": {"href": "/orders/1"}, "item": [{"href": "/items/2"}]},
+			"_embedded": {"customer": {"_links": {"self": {"href": "/customers/redacted"}}}}
+		}]}
+	}`, string(b))
+}